@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// errUnknownAlias is returned by ResolveAlias when no note declares the alias
+var errUnknownAlias = errors.New("no note has this alias")
+
+// noteAliases returns the aliases listed in a note's frontmatter under
+// aliases (or alias), in block, inline [a, b] or comma-separated form
+func noteAliases(content string) []string {
+	frontmatter, _, ok := splitFrontmatter(content)
+	if !ok {
+		return nil
+	}
+
+	var aliases []string
+	add := func(alias string) {
+		if alias = strings.Trim(strings.TrimSpace(alias), `"'`); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	inList := false
+	for _, line := range strings.Split(frontmatter, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inList && strings.HasPrefix(trimmed, "- ") {
+			add(trimmed[2:])
+			continue
+		}
+		inList = false
+
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if key = strings.TrimSpace(key); key != "aliases" && key != "alias" {
+			continue
+		}
+
+		value = strings.Trim(strings.TrimSpace(value), "[]")
+		if value == "" {
+			inList = true
+		}
+		for _, alias := range strings.Split(value, ",") {
+			add(alias)
+		}
+	}
+
+	return aliases
+}
+
+// aliasIndex maps lower-cased aliases to the vault-relative paths of the
+// notes declaring them. Aliases come from the note index, so only notes
+// that changed since the last scan are read.
+func (v *ObsidianVault) aliasIndex() map[string][]string {
+	notes, _ := v.scanIndex()
+
+	index := make(map[string][]string)
+	for relPath, entry := range notes {
+		for _, alias := range entry.aliases {
+			key := strings.ToLower(alias)
+			index[key] = append(index[key], relPath)
+		}
+	}
+	return index
+}
+
+// ResolveAlias returns the note whose frontmatter lists alias, matched
+// case-insensitively. An alias declared by several notes is an error
+// naming all of them.
+func (v *ObsidianVault) ResolveAlias(alias string) (string, error) {
+	candidates := v.aliasIndex()[strings.ToLower(strings.TrimSpace(alias))]
+
+	// A note listing the same alias twice is still one note
+	sort.Strings(candidates)
+	unique := candidates[:0]
+	for i, candidate := range candidates {
+		if i == 0 || candidate != candidates[i-1] {
+			unique = append(unique, candidate)
+		}
+	}
+
+	switch len(unique) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", errUnknownAlias, alias)
+	case 1:
+		return unique[0], nil
+	default:
+		return "", fmt.Errorf("alias %q is ambiguous, candidates: %s", alias, strings.Join(unique, ", "))
+	}
+}
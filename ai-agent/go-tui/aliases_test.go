@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNoteAliases(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"block", "---\naliases:\n  - GTD\n  - \"Getting Things Done\"\ntags: [x]\n---\nbody", []string{"GTD", "Getting Things Done"}},
+		{"inline", "---\naliases: [GTD, 'Getting Things Done']\n---\n", []string{"GTD", "Getting Things Done"}},
+		{"comma separated", "---\nalias: GTD, Inbox zero\n---\n", []string{"GTD", "Inbox zero"}},
+		{"none", "---\ntitle: x\n---\naliases: [not frontmatter]", nil},
+		{"no frontmatter", "aliases: [GTD]", nil},
+	}
+
+	for _, tc := range cases {
+		if got := noteAliases(tc.content); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: noteAliases = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestResolveAliasRoundTrip(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Methods/Getting Things Done.md": "---\naliases: [GTD, Inbox zero]\n---\n# GTD\n",
+		"Daily.md":                       "Processed the inbox with [[gtd]].\n",
+		"Other.md":                       "Nothing here.\n",
+	})
+
+	path, err := vault.ResolveAlias("gtd")
+	if err != nil || path != filepath.Join("Methods", "Getting Things Done.md") {
+		t.Fatalf("ResolveAlias(gtd) = %q, %v", path, err)
+	}
+
+	note, err := vault.ReadNote("Inbox zero.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Path != path || note.Title != "Getting Things Done" || !reflect.DeepEqual(note.Aliases, []string{"GTD", "Inbox zero"}) {
+		t.Errorf("ReadNote by alias = %+v", note)
+	}
+
+	backlinks, err := vault.GetBacklinks(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notePaths(backlinks); !reflect.DeepEqual(got, []string{"Daily.md"}) {
+		t.Errorf("backlinks = %v, want the [[gtd]] alias link", got)
+	}
+
+	if _, err := vault.ResolveAlias("Kanban"); !errors.Is(err, errUnknownAlias) {
+		t.Errorf("ResolveAlias(Kanban) = %v, want errUnknownAlias", err)
+	}
+	if _, err := vault.ReadNote("Kanban.md"); err == nil || !strings.Contains(err.Error(), "note not found") {
+		t.Errorf("ReadNote(Kanban.md) = %v, want note not found", err)
+	}
+}
+
+func TestResolveAliasAmbiguous(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Work/Plan.md": "---\naliases: [Plan]\n---\n",
+		"Home/Plan.md": "---\naliases: [plan, Plan]\n---\n",
+	})
+
+	_, err := vault.ResolveAlias("PLAN")
+	if err == nil {
+		t.Fatal("ResolveAlias(PLAN) succeeded, want an ambiguity error")
+	}
+	for _, candidate := range []string{filepath.Join("Home", "Plan.md"), filepath.Join("Work", "Plan.md")} {
+		if !strings.Contains(err.Error(), candidate) {
+			t.Errorf("error %q does not name %s", err, candidate)
+		}
+	}
+	if strings.Count(err.Error(), "Home") != 1 {
+		t.Errorf("error %q lists a note twice", err)
+	}
+}
+
+func TestResolveAliasFollowsEdits(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Note.md": "---\naliases: [Old]\n---\n",
+	})
+	if _, err := vault.ResolveAlias("Old"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A size change makes the index reread the note
+	if err := os.WriteFile(filepath.Join(vault.Path, "Note.md"), []byte("---\naliases: [Renamed]\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vault.ResolveAlias("Old"); !errors.Is(err, errUnknownAlias) {
+		t.Errorf("ResolveAlias(Old) after the edit = %v, want errUnknownAlias", err)
+	}
+	if path, err := vault.ResolveAlias("renamed"); err != nil || path != "Note.md" {
+		t.Errorf("ResolveAlias(renamed) = %q, %v", path, err)
+	}
+}
+
+func TestSearchNotesMatchesAliases(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Getting Things Done.md": "---\naliases: [GTD, Inbox zero]\n---\nA productivity method.\n",
+		"Other.md":               "Unrelated.\n",
+	})
+
+	results, err := vault.SearchNotes("inbox", false, "", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %+v, want the aliased note", results)
+	}
+	if !reflect.DeepEqual(results[0].Aliases, []string{"Inbox zero"}) {
+		t.Errorf("aliases = %v, want only the matching alias", results[0].Aliases)
+	}
+	if results[0].Preview != "" {
+		t.Errorf("preview = %q, want empty since only the frontmatter matched", results[0].Preview)
+	}
+}
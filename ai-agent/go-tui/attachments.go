@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxAttachmentBytes caps ReadAttachment unless the vault's
+// MaxAttachmentBytes or OBSIDIAN_MAX_ATTACHMENT_BYTES says otherwise
+const DefaultMaxAttachmentBytes = 5 * 1024 * 1024
+
+// AttachmentData is an attachment encoded for the model
+type AttachmentData struct {
+	Path     string `json:"path"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Data     string `json:"data"` // base64
+}
+
+func (v *ObsidianVault) maxAttachmentBytes() int64 {
+	if v.MaxAttachmentBytes > 0 {
+		return v.MaxAttachmentBytes
+	}
+	if n, err := strconv.ParseInt(os.Getenv("OBSIDIAN_MAX_ATTACHMENT_BYTES"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return DefaultMaxAttachmentBytes
+}
+
+func (v *ObsidianVault) allowAnyAttachment() bool {
+	return v.AnyAttachmentType || os.Getenv("OBSIDIAN_ANY_ATTACHMENT") == "1"
+}
+
+// ReadAttachment reads a file from the vault along with its MIME type, taken
+// from the extension or sniffed from the content. Files over the size cap,
+// and anything that isn't an image unless the vault allows any type, are
+// rejected.
+func (v *ObsidianVault) ReadAttachment(path string) (string, []byte, error) {
+	fullPath, err := v.resolveInVault(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return "", nil, fmt.Errorf("attachment not found: %s", path)
+	}
+	if limit := v.maxAttachmentBytes(); info.Size() > limit {
+		return "", nil, fmt.Errorf("attachment %s is %s, over the %s limit", path, formatBytes(int(info.Size())), formatBytes(int(limit)))
+	}
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(fullPath)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+
+	if !strings.HasPrefix(mimeType, "image/") && !v.allowAnyAttachment() {
+		return "", nil, fmt.Errorf("attachment %s is %s, only images can be read", path, mimeType)
+	}
+	return mimeType, data, nil
+}
+
+// ReadAttachmentBase64 is ReadAttachment encoded for a tool result
+func (v *ObsidianVault) ReadAttachmentBase64(path string) (*AttachmentData, error) {
+	mimeType, data, err := v.ReadAttachment(path)
+	if err != nil {
+		return nil, err
+	}
+	return &AttachmentData{
+		Path:     filepath.Clean(path),
+		MimeType: mimeType,
+		Size:     int64(len(data)),
+		Data:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditLogFile is the append-only log of vault changes, relative to the vault root
+const AuditLogFile = ".agent-audit.jsonl"
+
+// AuditShowEntries is how many entries /audit shows
+const AuditShowEntries = 10
+
+// AuditEntry is one line of the audit log
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Path      string    `json:"path"`
+	Summary   string    `json:"summary,omitempty"`
+}
+
+// audit appends an entry to the vault's audit log. A failed write is logged
+// and never fails the operation being audited.
+func (v *ObsidianVault) audit(operation, path, summary string) {
+	entry, err := json.Marshal(AuditEntry{
+		Time:      time.Now(),
+		Operation: operation,
+		Path:      path,
+		Summary:   summary,
+	})
+	if err == nil {
+		err = appendLine(filepath.Join(v.Path, AuditLogFile), entry)
+	}
+
+	if err != nil {
+		// Without AI_DEBUG a broken audit trail still has to show up
+		logger := v.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("audit log write failed", "operation", operation, "path", path, "error", err)
+	}
+}
+
+func appendLine(path string, line []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// RecentAudit returns the last n audit log entries, oldest first
+func (v *ObsidianVault) RecentAudit(n int) ([]AuditEntry, error) {
+	file, err := os.Open(filepath.Join(v.Path, AuditLogFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// lineCount counts lines for audit diff summaries
+func lineCount(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// coerceArguments fixes common type mistakes models make in tool arguments,
+// guided by the properties of the tool's Parameters schema:
+//
+//   - "true"/"false" strings for booleans
+//   - quoted numbers for integers and numbers
+//   - numbers and booleans for strings
+//   - a single value where an array is expected
+//
+// args is left untouched; the returned map is a copy when anything was
+// converted, along with a description of each conversion.
+func coerceArguments(schema map[string]interface{}, args map[string]interface{}) (map[string]interface{}, []string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if len(properties) == 0 || len(args) == 0 {
+		return args, nil
+	}
+
+	var coerced map[string]interface{}
+	var changes []string
+	for name, value := range args {
+		property, _ := properties[name].(map[string]interface{})
+		if property == nil {
+			continue
+		}
+
+		converted, ok := coerceValue(property, value)
+		if !ok {
+			continue
+		}
+		if coerced == nil {
+			coerced = make(map[string]interface{}, len(args))
+			for k, v := range args {
+				coerced[k] = v
+			}
+		}
+		coerced[name] = converted
+		changes = append(changes, fmt.Sprintf("%s: %#v -> %s", name, value, property["type"]))
+	}
+
+	if coerced == nil {
+		return args, nil
+	}
+	return coerced, changes
+}
+
+// coerceValue converts value to the type property declares, reporting false
+// when it already matches or can't be converted
+func coerceValue(property map[string]interface{}, value interface{}) (interface{}, bool) {
+	want, _ := property["type"].(string)
+
+	switch want {
+	case "boolean":
+		if s, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+
+	case "integer", "number":
+		// JSON numbers decode as float64, which is what the tools expect
+		if s, ok := value.(string); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f, true
+			}
+		}
+
+	case "string":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+
+	case "array":
+		list, isList := value.([]interface{})
+		if !isList {
+			if value == nil {
+				return nil, false
+			}
+			list = []interface{}{value}
+		}
+
+		items, _ := property["items"].(map[string]interface{})
+		out := make([]interface{}, len(list))
+		changed := !isList
+		for i, item := range list {
+			out[i] = item
+			if converted, ok := coerceValue(items, item); ok {
+				out[i] = converted
+				changed = true
+			}
+		}
+		if changed {
+			return out, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// isCommand reports whether the input is a slash command
+func isCommand(input string) bool {
+	return strings.HasPrefix(input, "/")
+}
+
+// handleCommand runs the slash command currently in the input
+func (m model) handleCommand() (tea.Model, tea.Cmd) {
+	input := strings.TrimSpace(m.input)
+	fields := strings.Fields(input)
+	m.input = ""
+	m.updateCompletions()
+	if len(fields) == 0 {
+		return m, nil
+	}
+
+	switch fields[0] {
+	case "/clear", "/reset":
+		// Keep only the initial greeting, and start the cost totals over
+		m.messages = append([]Message(nil), m.messages[:1]...)
+		m.turnCost, m.turnPriced = 0, false
+		m.sessionCost, m.sessionPriced = 0, false
+		m.addSystemMessage("Conversation cleared")
+
+	case "/context":
+		// Show or set the message-count cap for context sent to the provider
+		if len(fields) < 2 {
+			m.addSystemMessage(fmt.Sprintf("Context limit: %s", describeLimit(m.contextMessages, "messages")))
+			break
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 0 {
+			m.addSystemMessage("Usage: /context <n> (0 for unlimited)")
+			break
+		}
+		m.contextMessages = n
+		m.addSystemMessage(fmt.Sprintf("Context limit set to %s", describeLimit(n, "messages")))
+
+	case "/temp":
+		if len(fields) < 2 {
+			m.addSystemMessage(fmt.Sprintf("Temperature: %s", describeTemperature(m.sampling.Temperature)))
+			break
+		}
+		t, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || t < 0 || t > 2 {
+			m.addSystemMessage("Usage: /temp <0-2> (0 for provider default)")
+			break
+		}
+		m.sampling.Temperature = t
+		m.samplingChanged = true
+		m.applySampling()
+		m.addSystemMessage(fmt.Sprintf("Temperature set to %s", describeTemperature(t)))
+
+	case "/maxtokens":
+		if len(fields) < 2 {
+			m.addSystemMessage(fmt.Sprintf("Max tokens: %s", describeMaxTokens(m.sampling.MaxTokens)))
+			break
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 0 {
+			m.addSystemMessage("Usage: /maxtokens <n> (0 for provider default)")
+			break
+		}
+		m.sampling.MaxTokens = n
+		m.samplingChanged = true
+		m.applySampling()
+		m.addSystemMessage(fmt.Sprintf("Max tokens set to %s", describeMaxTokens(n)))
+
+	case "/model":
+		// Show or set the model of the selected provider, used from the next Ctrl+N
+		if len(fields) < 2 {
+			model := m.config.Model(m.providerType)
+			if model == "" {
+				model = "provider default"
+			}
+			m.addSystemMessage(fmt.Sprintf("Model for %s: %s", m.providerType, model))
+			break
+		}
+		if m.providerType == "azure" {
+			m.addSystemMessage("Azure uses its deployment (AZURE_OPENAI_DEPLOYMENT), not a model name")
+			break
+		}
+		m.config.SetModel(m.providerType, fields[1])
+		if m.state.Models == nil {
+			m.state.Models = make(map[string]string)
+		}
+		m.state.Models[m.providerType] = fields[1]
+		m.addSystemMessage(fmt.Sprintf("Model for %s set to %s, press Ctrl+N to reconnect", m.providerType, fields[1]))
+		if err := m.saveState(); err != nil {
+			m.addSystemMessage(fmt.Sprintf("⚠️ Could not save model selection: %v", err))
+		}
+
+	case "/readonly":
+		if m.vault == nil {
+			m.addSystemMessage("No vault loaded")
+			break
+		}
+		m.vault.ReadOnly = !m.vault.ReadOnly
+		if m.vault.ReadOnly {
+			m.addSystemMessage("🔒 Read-only mode on, vault writes are simulated")
+		} else {
+			m.addSystemMessage("🔓 Read-only mode off, vault writes are persisted")
+		}
+
+	case "/rawtools":
+		m.rawToolResults = !m.rawToolResults
+		if m.rawToolResults {
+			m.addSystemMessage("🔧 Tool results are shown as raw JSON")
+		} else {
+			m.addSystemMessage("🔧 Tool results are shown as summaries")
+		}
+
+	case "/export":
+		path, err := m.exportConversation(strings.Join(fields[1:], " "))
+		switch {
+		case err != nil:
+			m.addSystemMessage(fmt.Sprintf("Error exporting conversation: %v", err))
+		case m.vault.ReadOnly:
+			m.addSystemMessage(fmt.Sprintf("(dry run) would export conversation to %s", path))
+		default:
+			m.addSystemMessage(fmt.Sprintf("📝 Conversation exported to %s", path))
+		}
+
+	case "/audit":
+		if m.vault == nil {
+			m.addSystemMessage("No vault loaded")
+			break
+		}
+		entries, err := m.vault.RecentAudit(AuditShowEntries)
+		if err != nil {
+			m.addSystemMessage(fmt.Sprintf("Error reading audit log: %v", err))
+			break
+		}
+		if len(entries) == 0 {
+			m.addSystemMessage("Audit log is empty")
+			break
+		}
+		lines := []string{"📜 Recent vault changes:"}
+		for _, entry := range entries {
+			line := fmt.Sprintf("%s %s %s", entry.Time.Format("2006-01-02 15:04"), entry.Operation, entry.Path)
+			if entry.Summary != "" {
+				line += " (" + entry.Summary + ")"
+			}
+			lines = append(lines, line)
+		}
+		m.addSystemMessage(strings.Join(lines, "\n"))
+
+	case "/vault":
+		if len(fields) < 2 {
+			if m.vault == nil {
+				m.addSystemMessage("No vault loaded. Usage: /vault <path>")
+			} else {
+				m.addSystemMessage(fmt.Sprintf("Vault: %s", m.vault.Path))
+			}
+			break
+		}
+		// Paths may contain spaces, so take the rest of the raw input
+		path := strings.TrimSpace(strings.TrimPrefix(input, fields[0]))
+		if err := m.loadVault(path); err != nil {
+			m.addSystemMessage(fmt.Sprintf("Error loading vault: %v", err))
+			break
+		}
+		m.addSystemMessage(fmt.Sprintf("📚 Vault loaded: %s (%d tools)", m.vault.Path, m.tools.Len()))
+
+	case "/tools":
+		names := m.tools.Names()
+		if len(names) == 0 {
+			m.addSystemMessage("No tools registered (is the vault loaded?)")
+			break
+		}
+		lines := []string{fmt.Sprintf("🔧 %d tools available:", len(names))}
+		for _, name := range names {
+			tool, _ := m.tools.Get(name)
+			line := fmt.Sprintf("%s: %s", name, tool.Description)
+			if tool.Destructive {
+				line += " (asks for confirmation)"
+			}
+			lines = append(lines, line)
+		}
+		m.addSystemMessage(strings.Join(lines, "\n"))
+
+	default:
+		m.addSystemMessage(fmt.Sprintf("Unknown command: %s", fields[0]))
+	}
+
+	return m, nil
+}
+
+// loadVault switches to the vault at path and re-registers the tools for
+// it, keeping the current read-only setting. The current vault stays
+// loaded when path can't be opened.
+func (m *model) loadVault(path string) error {
+	vault, err := NewObsidianVault(path)
+	if err != nil {
+		return err
+	}
+
+	if m.vault != nil {
+		vault.ReadOnly = m.vault.ReadOnly
+	} else {
+		vault.ReadOnly = os.Getenv("OBSIDIAN_READONLY") == "1"
+	}
+	vault.Logger = m.logger
+	vault.Embedder = m.embedder
+
+	tools := NewToolRegistry()
+	tools.Logger = m.logger
+	RegisterObsidianTools(tools, vault)
+
+	m.vault, m.tools = vault, tools
+	m.completion = completion{dismissedAt: -1}
+	return nil
+}
+
+// saveState records the selected provider and writes the state file
+func (m *model) saveState() error {
+	m.state.Provider = m.providerType
+	return m.state.Save(m.statePath)
+}
+
+// addSystemMessage appends a system line to the conversation
+func (m *model) addSystemMessage(content string) {
+	m.messages = append(m.messages, Message{
+		Role:    "system",
+		Time:    time.Now(),
+		Content: content,
+	})
+}
+
+// describeLimit formats a limit where 0 means unlimited
+func describeLimit(n int, unit string) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+func describeTemperature(t float64) string {
+	if t == 0 {
+		return "provider default"
+	}
+	return strconv.FormatFloat(t, 'f', -1, 64)
+}
+
+func describeMaxTokens(n int) string {
+	if n == 0 {
+		return "provider default"
+	}
+	return strconv.Itoa(n)
+}
+
+// applySampling pushes the model's generation settings to the connected provider
+func (m *model) applySampling() {
+	if p, ok := m.provider.(interface{ SetSampling(Sampling) }); ok {
+		p.SetSampling(m.sampling)
+	}
+}
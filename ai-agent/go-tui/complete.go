@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MaxCompletions is how many suggestions the autocomplete popup shows
+const MaxCompletions = 5
+
+type completionKind int
+
+const (
+	completeNone completionKind = iota
+	completeNote
+	completeTag
+)
+
+// completion is the autocomplete state for the token at the end of the input
+type completion struct {
+	kind     completionKind
+	start    int // where the query starts in the input
+	items    []string
+	selected int
+
+	// Trigger start the user dismissed with Esc, -1 when none
+	dismissedAt int
+
+	// Candidates loaded from the vault in the background when a popup
+	// first opens; the previous ones are used until the load finishes
+	notes []string
+	tags  []string
+}
+
+// completionCandidatesMsg carries note titles and tags loaded for the popup
+type completionCandidatesMsg struct {
+	vault *ObsidianVault
+	notes []string
+	tags  []string
+}
+
+// completionToken finds an unfinished [[wikilink or #tag at the end of the
+// input, returning its kind, where the query starts and the query itself
+func completionToken(input string) (completionKind, int, string) {
+	if i := strings.LastIndex(input, "[["); i >= 0 && !strings.Contains(input[i:], "]]") {
+		return completeNote, i + 2, input[i+2:]
+	}
+
+	j := strings.LastIndexAny(input, " \t\n") + 1
+	if token := input[j:]; strings.HasPrefix(token, "#") {
+		return completeTag, j + 1, token[1:]
+	}
+
+	return completeNone, 0, ""
+}
+
+// updateCompletions recomputes suggestions after the input changed. When a
+// new popup opens it returns a command that refreshes the candidates.
+func (m *model) updateCompletions() tea.Cmd {
+	c := &m.completion
+	kind, start, query := completionToken(m.input)
+	if kind == completeNone || m.vault == nil || start == c.dismissedAt {
+		if kind == completeNone {
+			c.dismissedAt = -1
+		}
+		c.kind, c.items = completeNone, nil
+		return nil
+	}
+
+	// Refresh candidates whenever a new popup opens
+	var cmd tea.Cmd
+	if c.kind == completeNone || c.start != start {
+		cmd = loadCompletionCandidates(m.vault)
+	}
+
+	candidates := c.notes
+	if kind == completeTag {
+		candidates = c.tags
+	}
+
+	query = strings.ToLower(query)
+	var items []string
+	for _, candidate := range candidates {
+		if strings.Contains(strings.ToLower(candidate), query) {
+			items = append(items, candidate)
+			if len(items) == MaxCompletions {
+				break
+			}
+		}
+	}
+
+	c.kind, c.start, c.items = kind, start, items
+	if c.selected >= len(items) {
+		c.selected = 0
+	}
+	return cmd
+}
+
+// loadCompletionCandidates reads note titles and tags from the vault off the
+// UI goroutine, since both walk the whole vault
+func loadCompletionCandidates(vault *ObsidianVault) tea.Cmd {
+	return func() tea.Msg {
+		msg := completionCandidatesMsg{vault: vault}
+
+		if notes, err := vault.ListNotes("", true); err == nil {
+			for _, note := range notes {
+				msg.notes = append(msg.notes, note.Title)
+			}
+		}
+
+		if tags, err := vault.GetTags(); err == nil {
+			for tag := range tags {
+				msg.tags = append(msg.tags, tag)
+			}
+			sort.Strings(msg.tags)
+		}
+
+		return msg
+	}
+}
+
+// setCompletionCandidates stores freshly loaded candidates and refilters the
+// open popup, ignoring loads for a vault that has since been replaced
+func (m *model) setCompletionCandidates(msg completionCandidatesMsg) {
+	if msg.vault != m.vault {
+		return
+	}
+	m.completion.notes, m.completion.tags = msg.notes, msg.tags
+	m.updateCompletions()
+}
+
+// completionVisible reports whether the popup has suggestions to show
+func (m model) completionVisible() bool {
+	return len(m.completion.items) > 0
+}
+
+// moveCompletion moves the popup selection by delta, wrapping around
+func (m *model) moveCompletion(delta int) {
+	c := &m.completion
+	c.selected = (c.selected + delta + len(c.items)) % len(c.items)
+}
+
+// acceptCompletion replaces the query with the selected suggestion and
+// completes the token
+func (m *model) acceptCompletion() {
+	c := &m.completion
+	item := c.items[c.selected]
+
+	switch c.kind {
+	case completeNote:
+		m.input = m.input[:c.start] + item + "]]"
+	case completeTag:
+		m.input = m.input[:c.start] + item + " "
+	}
+
+	c.kind, c.items, c.selected = completeNone, nil, 0
+}
+
+// dismissCompletion hides the popup until a new trigger token is typed
+func (m *model) dismissCompletion() {
+	c := &m.completion
+	c.dismissedAt = c.start
+	c.kind, c.items, c.selected = completeNone, nil, 0
+}
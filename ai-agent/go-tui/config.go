@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProviderTypes are the providers Ctrl+P cycles through, in order
+var ProviderTypes = []string{"openai", "anthropic", "ollama", "azure"}
+
+// Config is the optional ~/.config/ai-agent/config.toml. Environment
+// variables override the file and command-line flags override both.
+type Config struct {
+	VaultPath string `toml:"vault_path"`
+	Provider  string `toml:"provider"`
+
+	// Path to a file whose contents are sent as the system prompt
+	SystemPrompt string `toml:"system_prompt"`
+
+	// Per-provider settings keyed by provider type, e.g. [providers.ollama]
+	Providers map[string]ProviderConfig `toml:"providers"`
+
+	// Price overrides on top of DefaultPricing, e.g. [pricing.openai."gpt-4o"]
+	Pricing Pricing `toml:"pricing"`
+}
+
+// ProviderConfig holds the per-provider settings of the config file. Zero
+// values keep the built-in defaults.
+type ProviderConfig struct {
+	Model       string  `toml:"model"`
+	Temperature float64 `toml:"temperature"`
+	MaxTokens   int     `toml:"max_tokens"`
+}
+
+// DefaultConfigPath is config.toml under the user config dir (~/.config/ai-agent on Linux)
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ai-agent", "config.toml")
+}
+
+// LoadConfig reads the config file at path and applies environment
+// overrides. A missing file silently yields the defaults.
+func LoadConfig(path string) (Config, error) {
+	cfg := Config{}
+
+	if path != "" {
+		_, err := toml.DecodeFile(path, &cfg)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return Config{}, fmt.Errorf("reading config %s: %w", path, err)
+		}
+	}
+
+	if v := os.Getenv("OBSIDIAN_VAULT_PATH"); v != "" {
+		cfg.VaultPath = v
+	}
+	if v := os.Getenv("AI_PROVIDER"); v != "" {
+		cfg.Provider = v
+	}
+	if v := os.Getenv("AI_SYSTEM_PROMPT"); v != "" {
+		cfg.SystemPrompt = v
+	}
+
+	if cfg.VaultPath == "" {
+		cfg.VaultPath = os.Getenv("HOME") + "/Documents/Obsidian"
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "openai"
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the settings that have no sensible fallback
+func (c Config) Validate() error {
+	for _, p := range ProviderTypes {
+		if c.Provider == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown provider %q, expected one of: %s", c.Provider, strings.Join(ProviderTypes, ", "))
+}
+
+// Model returns the configured model for providerType, empty for the default
+func (c Config) Model(providerType string) string {
+	return c.Providers[providerType].Model
+}
+
+// SetModel changes the model used for providerType from now on
+func (c *Config) SetModel(providerType, model string) {
+	if c.Providers == nil {
+		c.Providers = make(map[string]ProviderConfig)
+	}
+	pc := c.Providers[providerType]
+	pc.Model = model
+	c.Providers[providerType] = pc
+}
+
+// Sampling returns the generation settings for providerType: the config
+// file values, overridden by AI_TEMPERATURE and AI_MAX_TOKENS
+func (c Config) Sampling(providerType string) Sampling {
+	pc := c.Providers[providerType]
+	sampling := Sampling{Temperature: pc.Temperature, MaxTokens: pc.MaxTokens}
+
+	env := SamplingFromEnv()
+	if env.Temperature != 0 {
+		sampling.Temperature = env.Temperature
+	}
+	if env.MaxTokens != 0 {
+		sampling.MaxTokens = env.MaxTokens
+	}
+	return sampling
+}
+
+// loadSystemPrompt reads the system prompt file, returning "" when none is configured
+func (c Config) loadSystemPrompt() (string, error) {
+	if c.SystemPrompt == "" {
+		return "", nil
+	}
+	path, err := expandPath(c.SystemPrompt)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Debug log rotation limits
+const (
+	DebugLogMaxSize = 5 << 20 // bytes per file
+	DebugLogBackups = 3
+)
+
+// redactedHeaders carry API keys and are never written to the debug log
+var redactedHeaders = []string{"Authorization", "X-Api-Key", "Api-Key"}
+
+// NewDebugLogger opens the provider debug log under ~/.cache/ai-agent/ when
+// AI_DEBUG=1. It returns a nil logger when debugging is off.
+func NewDebugLogger() (*slog.Logger, error) {
+	if os.Getenv("AI_DEBUG") != "1" {
+		return nil, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(cacheDir, "ai-agent")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := openRotatingFile(filepath.Join(dir, "debug.log"), DebugLogMaxSize, DebugLogBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	return slog.New(slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug})), nil
+}
+
+// rotatingFile is an append-only log file that is renamed to path.1, path.2,
+// ... once it grows past maxSize
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+
+	for i := r.backups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return r.open()
+}
+
+// logRequest records an outgoing provider request with API keys redacted
+func logRequest(logger *slog.Logger, provider string, req *http.Request, body []byte) {
+	if logger == nil {
+		return
+	}
+	logger.Debug("provider request",
+		"provider", provider,
+		"method", req.Method,
+		"url", req.URL.String(),
+		"headers", redactHeaders(req.Header),
+		"body", string(body),
+	)
+}
+
+// logResponse records a provider response status and body. Streamed
+// responses are logged as their assembled content.
+func logResponse(logger *slog.Logger, provider string, status int, body string) {
+	if logger == nil {
+		return
+	}
+	logger.Debug("provider response",
+		"provider", provider,
+		"status", status,
+		"body", body,
+	)
+}
+
+// logAPIError records a failed response and returns the error unchanged
+func logAPIError(logger *slog.Logger, apiErr *APIError) *APIError {
+	logResponse(logger, apiErr.Provider, apiErr.StatusCode, apiErr.Message)
+	return apiErr
+}
+
+// redactHeaders flattens headers for logging, hiding credential values
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for key, values := range header {
+		out[key] = strings.Join(values, ", ")
+	}
+	for _, key := range redactedHeaders {
+		if _, ok := out[key]; ok {
+			out[key] = "[REDACTED]"
+		}
+	}
+	return out
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Semantic search settings
+const (
+	// EmbeddingCacheFile stores note embeddings by content hash, relative to the vault root
+	EmbeddingCacheFile = ".agent-embeddings.json"
+
+	DefaultSemanticTopK = 5
+	MaxSemanticTopK     = 25
+
+	// Notes are cut to this many bytes before embedding to stay within model limits
+	EmbedMaxChars = 8000
+
+	// Texts sent per embedding request
+	EmbedBatchSize = 64
+
+	// Upper bound for one search, including embedding any changed notes
+	EmbedTimeout = 5 * time.Minute
+)
+
+// Embedder turns texts into embedding vectors, one per text in order
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+	// EmbeddingModel names the model, cached vectors from another model are discarded
+	EmbeddingModel() string
+}
+
+// NewEmbedderFromEnv picks the embedding backend from AI_EMBEDDER ("openai"
+// or "ollama"). Unset, it uses OpenAI when OPENAI_API_KEY is set and Ollama
+// otherwise.
+func NewEmbedderFromEnv(logger *slog.Logger) (Embedder, error) {
+	backend := os.Getenv("AI_EMBEDDER")
+	if backend == "" {
+		backend = "ollama"
+		if os.Getenv("OPENAI_API_KEY") != "" {
+			backend = "openai"
+		}
+	}
+
+	switch backend {
+	case "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY not set")
+		}
+		baseURL := os.Getenv("OPENAI_BASE_URL")
+		if baseURL == "" {
+			baseURL = DefaultOpenAIBaseURL
+		}
+		if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid OPENAI_BASE_URL: %q", baseURL)
+		}
+		return &OpenAIEmbedder{
+			APIKey:  apiKey,
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Model:   modelOrDefault(os.Getenv("OPENAI_EMBED_MODEL"), "text-embedding-3-small"),
+			Logger:  logger,
+		}, nil
+
+	case "ollama":
+		baseURL := os.Getenv("OLLAMA_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+		return &OllamaEmbedder{
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Model:   modelOrDefault(os.Getenv("OLLAMA_EMBED_MODEL"), "nomic-embed-text"),
+			Logger:  logger,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown embedder: %s", backend)
+	}
+}
+
+// OpenAIEmbedder implements Embedder with the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	APIKey  string
+	BaseURL string // empty means DefaultOpenAIBaseURL
+	Model   string
+	Client  *http.Client
+	Logger  *slog.Logger
+}
+
+func (e *OpenAIEmbedder) EmbeddingModel() string {
+	return "openai/" + e.Model
+}
+
+// endpoint joins path onto the base URL like OpenAIProvider.endpoint
+func (e *OpenAIEmbedder) endpoint(path string) string {
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	err := postEmbeddings(ctx, e.Client, e.Logger, "openai", e.endpoint("embeddings"), map[string]string{
+		"Authorization": "Bearer " + e.APIKey,
+	}, map[string]interface{}{
+		"model": e.Model,
+		"input": texts,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	return vectors, checkEmbeddings(vectors)
+}
+
+// OllamaEmbedder implements Embedder with a local Ollama embedding model
+type OllamaEmbedder struct {
+	BaseURL string
+	Model   string
+	Client  *http.Client
+	Logger  *slog.Logger
+}
+
+func (e *OllamaEmbedder) EmbeddingModel() string {
+	return "ollama/" + e.Model
+}
+
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	err := postEmbeddings(ctx, e.Client, e.Logger, "ollama", e.BaseURL+"/api/embed", nil, map[string]interface{}{
+		"model": e.Model,
+		"input": texts,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+	}
+	return resp.Embeddings, checkEmbeddings(resp.Embeddings)
+}
+
+// postEmbeddings sends an embedding request and decodes the JSON response into out
+func postEmbeddings(ctx context.Context, client *http.Client, logger *slog.Logger, provider, url string, headers map[string]string, req interface{}, out interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	// Note text is not logged, only its size
+	logRequest(logger, provider, httpReq, []byte(fmt.Sprintf("(embedding request, %d bytes)", len(body))))
+
+	resp, err := httpClient(client).Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return logAPIError(logger, newAPIError(provider, resp))
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	logResponse(logger, provider, resp.StatusCode, fmt.Sprintf("(embedding response, %d bytes)", len(respBody)))
+
+	return json.Unmarshal(respBody, out)
+}
+
+func checkEmbeddings(vectors [][]float32) error {
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("no embedding returned for text %d", i)
+		}
+	}
+	return nil
+}
+
+// embeddingCache is the on-disk EmbeddingCacheFile
+type embeddingCache struct {
+	Model string                    `json:"model"`
+	Notes map[string]embeddingEntry `json:"notes"` // by note path
+}
+
+type embeddingEntry struct {
+	Hash   string    `json:"hash"`
+	Vector []float32 `json:"vector"`
+}
+
+// loadEmbeddingCache reads the vault's cache, starting empty when it is
+// missing, unreadable or was built with a different model
+func (v *ObsidianVault) loadEmbeddingCache(model string) *embeddingCache {
+	cache := &embeddingCache{}
+	if data, err := os.ReadFile(filepath.Join(v.Path, EmbeddingCacheFile)); err == nil {
+		json.Unmarshal(data, cache)
+	}
+	if cache.Model != model || cache.Notes == nil {
+		cache = &embeddingCache{Model: model, Notes: make(map[string]embeddingEntry)}
+	}
+	return cache
+}
+
+func (v *ObsidianVault) saveEmbeddingCache(cache *embeddingCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(v.Path, EmbeddingCacheFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SemanticSearch returns the topK notes whose embeddings are closest to the
+// query's, scored by cosine similarity. Notes are embedded on first use and
+// re-embedded only when their content changes; the cache is kept under the
+// vault unless it is read-only.
+func (v *ObsidianVault) SemanticSearch(query string, topK int) ([]NoteInfo, error) {
+	if v.Embedder == nil {
+		return nil, fmt.Errorf("semantic search is unavailable: no embedding backend configured")
+	}
+	if topK <= 0 {
+		topK = DefaultSemanticTopK
+	}
+	if topK > MaxSemanticTopK {
+		topK = MaxSemanticTopK
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), EmbedTimeout)
+	defer cancel()
+
+	v.embedMu.Lock()
+	defer v.embedMu.Unlock()
+
+	model := v.Embedder.EmbeddingModel()
+	if v.embeddings == nil || v.embeddings.Model != model {
+		v.embeddings = v.loadEmbeddingCache(model)
+	}
+	cache := v.embeddings
+
+	index, err := v.scanIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	// Find notes that are new or changed since they were embedded. The index
+	// already knows every note's content hash, so only those are read.
+	var stalePaths, staleTexts []string
+	for path, note := range index {
+		if entry, ok := cache.Notes[path]; ok && entry.Hash == note.hash {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(v.Path, path))
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSuffix(filepath.Base(path), ".md") + "\n\n" + string(data)
+		text = truncateUTF8(text, EmbedMaxChars)
+		stalePaths = append(stalePaths, path)
+		staleTexts = append(staleTexts, text)
+		cache.Notes[path] = embeddingEntry{Hash: contentHash(data)}
+	}
+
+	changed := len(stalePaths) > 0
+	for path := range cache.Notes {
+		if _, ok := index[path]; !ok {
+			delete(cache.Notes, path)
+			changed = true
+		}
+	}
+
+	for start := 0; start < len(staleTexts); start += EmbedBatchSize {
+		end := start + EmbedBatchSize
+		if end > len(staleTexts) {
+			end = len(staleTexts)
+		}
+		vectors, err := v.Embedder.Embed(ctx, staleTexts[start:end])
+		if err != nil {
+			// Drop the half-filled entries so they are retried next time
+			for _, path := range stalePaths[start:] {
+				delete(cache.Notes, path)
+			}
+			return nil, fmt.Errorf("embedding notes: %w", err)
+		}
+		for i, vector := range vectors {
+			path := stalePaths[start+i]
+			entry := cache.Notes[path]
+			entry.Vector = vector
+			cache.Notes[path] = entry
+		}
+	}
+
+	if changed && !v.ReadOnly {
+		if err := v.saveEmbeddingCache(cache); err != nil && v.Logger != nil {
+			v.Logger.Warn("embedding cache write failed", "error", err)
+		}
+	}
+
+	queryVectors, err := v.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embedding query: %w", err)
+	}
+	queryVector := queryVectors[0]
+
+	results := make([]NoteInfo, 0, len(cache.Notes))
+	for path, entry := range cache.Notes {
+		results = append(results, NoteInfo{
+			Path:        path,
+			Title:       strings.TrimSuffix(filepath.Base(path), ".md"),
+			ContentHash: entry.Hash,
+			Score:       cosineSimilarity(queryVector, entry.Vector),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	for i := range results {
+		if data, err := os.ReadFile(filepath.Join(v.Path, results[i].Path)); err == nil {
+			results[i].Preview = notePreview(string(data), 200)
+		}
+	}
+	return results, nil
+}
+
+// cosineSimilarity of two vectors, 0 when their lengths differ or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Sentinel errors for common provider failures, matched with errors.Is
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("provider server error")
+)
+
+// APIError is a failed provider API call
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Type != "" {
+		return fmt.Sprintf("%s API error (%d %s): %s", e.Provider, e.StatusCode, e.Type, msg)
+	}
+	return fmt.Sprintf("%s API error (%d): %s", e.Provider, e.StatusCode, msg)
+}
+
+// Is maps status codes to the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// newAPIError reads an error response body into an APIError. It understands
+// the OpenAI/Anthropic {"error": {"type", "message"}} shape and Ollama's
+// {"error": "message"}, falling back to the raw body.
+func newAPIError(provider string, resp *http.Response) *APIError {
+	apiErr := &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var structured struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	var simple struct {
+		Error string `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &structured); err == nil && structured.Error.Message != "" {
+		apiErr.Type = structured.Error.Type
+		apiErr.Message = structured.Error.Message
+	} else if err := json.Unmarshal(body, &simple); err == nil && simple.Error != "" {
+		apiErr.Message = simple.Error
+	} else {
+		apiErr.Message = string(body)
+	}
+
+	return apiErr
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// renderConversation formats the conversation as a markdown document with a
+// section per user/assistant turn and tool uses in collapsible blocks
+func renderConversation(messages []Message) string {
+	var b strings.Builder
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "user":
+			b.WriteString("## You\n\n")
+			b.WriteString(msg.Content)
+			b.WriteString("\n\n")
+		case "assistant":
+			b.WriteString("## Assistant")
+			if label := messageSource(msg); label != "" {
+				b.WriteString(" (" + label + ")")
+			}
+			b.WriteString("\n\n")
+			b.WriteString(msg.Content)
+			b.WriteString("\n\n")
+		case "system":
+			if strings.HasPrefix(msg.Content, "🔧") {
+				b.WriteString("<details>\n<summary>Tool use</summary>\n\n")
+				b.WriteString(msg.Content)
+				b.WriteString("\n\n</details>\n\n")
+			}
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// exportConversation saves the conversation as a note tagged "conversation"
+func (m model) exportConversation(title string) (string, error) {
+	if m.vault == nil {
+		return "", fmt.Errorf("no vault loaded")
+	}
+
+	if title == "" {
+		title = "Conversation " + time.Now().Format("2006-01-02 1504")
+	}
+
+	meta := map[string]string{
+		"provider": m.providerType,
+	}
+	if m.provider != nil {
+		meta["model"] = ProviderModel(m.provider)
+	}
+
+	// A second export under the same title gets a -N suffix
+	return m.vault.CreateNoteWithMeta(title, renderConversation(m.messages), "", []string{"conversation"}, meta, OnConflictSuffix)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fuzzy title search limits
+const (
+	DefaultFuzzyLimit = 10
+	MaxFuzzyLimit     = 50
+
+	// Matches scoring below this are too loose to be useful
+	MinFuzzyScore = 0.3
+)
+
+// FuzzyFindNotes ranks note titles by how closely they match query and
+// returns the best limit matches, each with its score in (0, 1]
+func (v *ObsidianVault) FuzzyFindNotes(query string, limit int) ([]NoteInfo, error) {
+	if limit <= 0 {
+		limit = DefaultFuzzyLimit
+	}
+	if limit > MaxFuzzyLimit {
+		limit = MaxFuzzyLimit
+	}
+
+	notes, err := v.ListNotes("", true)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matches []NoteInfo
+	for _, note := range notes {
+		score := fuzzyScore(query, strings.ToLower(note.Title))
+		if score < MinFuzzyScore {
+			continue
+		}
+		matches = append(matches, NoteInfo{
+			Path:     note.Path,
+			Title:    note.Title,
+			Modified: note.Modified,
+			Score:    score,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// fuzzyScore rates how well title matches query, both lower-cased. Exact
+// and substring matches rank highest, then in-order subsequences and
+// near-misses by edit distance.
+func fuzzyScore(query, title string) float64 {
+	if query == "" || title == "" {
+		return 0
+	}
+	if query == title {
+		return 1
+	}
+
+	queryLen, titleLen := utf8.RuneCountInString(query), utf8.RuneCountInString(title)
+	if strings.Contains(title, query) {
+		return 0.7 + 0.25*float64(queryLen)/float64(titleLen)
+	}
+
+	score := 0.0
+	if span := subsequenceSpan(query, title); span > 0 {
+		score = 0.6 * float64(queryLen) / float64(span)
+	}
+
+	longest := queryLen
+	if titleLen > longest {
+		longest = titleLen
+	}
+	if similarity := 0.6 * (1 - float64(levenshtein(query, title))/float64(longest)); similarity > score {
+		score = similarity
+	}
+	return score
+}
+
+// subsequenceSpan returns the length of the shortest stretch of title,
+// starting at the first match, containing query's runes in order, or 0
+// if query is not a subsequence of title
+func subsequenceSpan(query, title string) int {
+	q := []rune(query)
+	start, i := -1, 0
+	for pos, r := range []rune(title) {
+		if r != q[i] {
+			continue
+		}
+		if start < 0 {
+			start = pos
+		}
+		i++
+		if i == len(q) {
+			return pos - start + 1
+		}
+	}
+	return 0
+}
+
+// levenshtein is the edit distance between a and b in runes
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -3,8 +3,11 @@ module obsidian-agent
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/atotto/clipboard v0.1.4
 	github.com/charmbracelet/bubbletea v0.25.0
 	github.com/charmbracelet/lipgloss v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
 )
 
 require (
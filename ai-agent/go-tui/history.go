@@ -0,0 +1,58 @@
+package main
+
+// Default sliding-window limits for conversation context, 0 means unlimited
+const (
+	DefaultContextMessages = 40
+	DefaultContextTokens   = 0
+)
+
+// estimateTokens gives a rough token count for a message (~4 characters per token)
+func estimateTokens(msg ChatMessage) int {
+	return len(msg.Content)/4 + 1
+}
+
+// trimContext drops the oldest exchanges, each a user message and the
+// replies after it, until the non-system messages fit within maxMessages
+// and maxTokens (0 disables a limit). Leading system messages and the most
+// recent exchange are always kept, and a trimmed history starts on a user
+// message, which Anthropic requires. It returns the trimmed history and
+// how many messages were dropped.
+func trimContext(messages []ChatMessage, maxMessages, maxTokens int) ([]ChatMessage, int) {
+	var system, rest []ChatMessage
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+
+	tokens := 0
+	for _, msg := range messages {
+		tokens += estimateTokens(msg)
+	}
+
+	// Where each exchange starts; anything before the first user message
+	// counts as one more exchange and goes first
+	var starts []int
+	for i, msg := range rest {
+		if i == 0 || msg.Role == "user" {
+			starts = append(starts, i)
+		}
+	}
+
+	dropped := 0
+	for _, start := range starts[min(1, len(starts)):] {
+		overMessages := maxMessages > 0 && len(rest)-dropped > maxMessages
+		overTokens := maxTokens > 0 && tokens > maxTokens
+		if !overMessages && !overTokens {
+			break
+		}
+		for _, msg := range rest[dropped:start] {
+			tokens -= estimateTokens(msg)
+		}
+		dropped = start
+	}
+
+	return append(system, rest[dropped:]...), dropped
+}
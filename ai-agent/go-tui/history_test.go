@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// conversation builds a system prompt followed by n messages alternating
+// user/assistant, starting with user
+func conversation(n int) []ChatMessage {
+	messages := []ChatMessage{{Role: "system", Content: "prompt"}}
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, ChatMessage{Role: role, Content: strings.Repeat("x", 40)})
+	}
+	return messages
+}
+
+func TestTrimContextStartsOnUserMessage(t *testing.T) {
+	// 41 messages ending in a user turn; dropping one message alone would
+	// leave an assistant message first
+	trimmed, dropped := trimContext(conversation(41), 40, 0)
+
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2 (one whole exchange)", dropped)
+	}
+	if trimmed[0].Role != "system" {
+		t.Errorf("first message role = %q, want the system prompt kept", trimmed[0].Role)
+	}
+	if trimmed[1].Role != "user" {
+		t.Errorf("window starts with %q, want user", trimmed[1].Role)
+	}
+	if got := len(trimmed) - 1; got > 40 {
+		t.Errorf("kept %d messages, want at most 40", got)
+	}
+}
+
+func TestTrimContextKeepsLastExchange(t *testing.T) {
+	messages := []ChatMessage{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "reply"},
+		{Role: "user", Content: "look it up"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Name: "search"}}},
+		{Role: "tool", ToolCallID: "1", Content: "result"},
+	}
+
+	trimmed, dropped := trimContext(messages, 1, 0)
+
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+	if len(trimmed) != 3 || trimmed[0].Content != "look it up" {
+		t.Errorf("trimmed = %+v, want the whole last exchange", trimmed)
+	}
+}
+
+func TestTrimContextTokenBudget(t *testing.T) {
+	// Each message is ~11 tokens, the system prompt ~2
+	trimmed, dropped := trimContext(conversation(6), 0, 30)
+
+	if dropped != 4 {
+		t.Errorf("dropped = %d, want 4", dropped)
+	}
+	if len(trimmed) != 3 || trimmed[1].Role != "user" {
+		t.Errorf("trimmed = %+v, want system plus the last exchange", trimmed)
+	}
+}
+
+func TestTrimContextWithinLimits(t *testing.T) {
+	messages := conversation(5)
+	trimmed, dropped := trimContext(messages, 40, 0)
+	if dropped != 0 || len(trimmed) != len(messages) {
+		t.Errorf("trimmed %d of %d messages, want none", dropped, len(messages))
+	}
+
+	if _, dropped := trimContext(nil, 1, 1); dropped != 0 {
+		t.Errorf("empty history dropped %d", dropped)
+	}
+}
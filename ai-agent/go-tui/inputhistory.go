@@ -0,0 +1,47 @@
+package main
+
+// inputHistory is the inputs sent this session, recalled with Up/Down like
+// a shell. index is the entry shown in the input, len(entries) when it
+// holds the draft, which is kept aside while browsing. Recalled entries
+// are copied into the input, so editing one leaves the history unchanged.
+type inputHistory struct {
+	entries []string
+	index   int
+	draft   string
+}
+
+// add records a sent input, skipping a repeat of the last one, and goes
+// back to an empty draft
+func (h *inputHistory) add(input string) {
+	if n := len(h.entries); n == 0 || h.entries[n-1] != input {
+		h.entries = append(h.entries, input)
+	}
+	h.index = len(h.entries)
+	h.draft = ""
+}
+
+// prev steps back to the previous entry, saving current as the draft when
+// leaving it. It reports false at the oldest entry.
+func (h *inputHistory) prev(current string) (string, bool) {
+	if h.index == 0 {
+		return "", false
+	}
+	if h.index == len(h.entries) {
+		h.draft = current
+	}
+	h.index--
+	return h.entries[h.index], true
+}
+
+// next steps forward to the next entry, or back to the draft after the
+// newest one. It reports false when already at the draft.
+func (h *inputHistory) next() (string, bool) {
+	if h.index >= len(h.entries) {
+		return "", false
+	}
+	h.index++
+	if h.index == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.index], true
+}
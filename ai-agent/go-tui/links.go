@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Link types reported by OutgoingLinks
+const (
+	LinkWiki     = "wikilink"
+	LinkMarkdown = "markdown"
+	LinkEmbed    = "embed"
+)
+
+// Link is one outgoing reference of a note
+type Link struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`           // as written, without alias
+	Anchor string `json:"anchor,omitempty"` // heading or ^block after #
+	Alias  string `json:"alias,omitempty"`  // wikilink alias or markdown link text
+
+	// Vault-relative path the link points to, empty when it doesn't exist
+	Resolved string `json:"resolved,omitempty"`
+	External bool   `json:"external"`
+
+	markdownSyntax bool // [text](target) rather than [[target]], decides resolution
+}
+
+var (
+	wikiLinkPattern     = regexp.MustCompile(`(!?)\[\[([^\[\]]+)\]\]`)
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[([^\[\]]*)\]\(<?([^)<>\s]+)>?(?:\s+"[^"]*")?\)`)
+	externalLinkPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// OutgoingLinks lists the wikilinks, markdown links and embeds in a note,
+// once each, line by line. Internal targets are resolved to vault paths;
+// external URLs are reported as-is. Fenced code blocks are skipped.
+func (v *ObsidianVault) OutgoingLinks(notePath string) ([]Link, error) {
+	content, err := os.ReadFile(filepath.Join(v.Path, notePath))
+	if err != nil {
+		return nil, fmt.Errorf("note not found: %s", notePath)
+	}
+
+	links := parseLinks(string(content))
+	v.resolveLinks(notePath, links, v.linkIndex())
+	return links, nil
+}
+
+// parseLinks extracts the links of a note once each, in order, leaving
+// Resolved empty. Fenced code blocks are skipped.
+func parseLinks(content string) []Link {
+	var links []Link
+	seen := make(map[Link]bool)
+	add := func(link Link) {
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, m := range wikiLinkPattern.FindAllStringSubmatch(line, -1) {
+			link := Link{Type: LinkWiki}
+			if m[1] == "!" {
+				link.Type = LinkEmbed
+			}
+			target, alias, _ := strings.Cut(m[2], "|")
+			link.Target, link.Anchor, _ = strings.Cut(strings.TrimSpace(target), "#")
+			link.Alias = strings.TrimSpace(alias)
+			add(link)
+		}
+
+		for _, m := range markdownLinkPattern.FindAllStringSubmatch(line, -1) {
+			link := Link{Type: LinkMarkdown, Alias: m[2], markdownSyntax: true}
+			if m[1] == "!" {
+				link.Type = LinkEmbed
+			}
+			if externalLinkPattern.MatchString(m[3]) {
+				link.Target = m[3]
+				link.External = true
+				add(link)
+				continue
+			}
+			link.Target, link.Anchor, _ = strings.Cut(m[3], "#")
+			add(link)
+		}
+	}
+
+	return links
+}
+
+// resolveLinks fills in Resolved for the internal links of the note at
+// notePath. A link without a target ([[#Heading]]) points into the note itself.
+func (v *ObsidianVault) resolveLinks(notePath string, links []Link, index linkIndex) {
+	noteDir := filepath.Dir(notePath)
+	for i := range links {
+		link := &links[i]
+		switch {
+		case link.External:
+		case link.Target == "":
+			link.Resolved = notePath
+		case link.markdownSyntax:
+			link.Resolved = v.resolveMarkdownLink(noteDir, link.Target)
+		default:
+			link.Resolved = index.resolveWikilink(link.Target)
+		}
+	}
+}
+
+// linkIndex maps lower-cased file names, with and without .md, to the
+// vault-relative paths carrying them
+type linkIndex map[string][]string
+
+func (v *ObsidianVault) linkIndex() linkIndex {
+	index := make(linkIndex)
+	filepath.Walk(v.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != v.Path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, _ := filepath.Rel(v.Path, path)
+		name := strings.ToLower(info.Name())
+		index[name] = append(index[name], relPath)
+		if strings.HasSuffix(name, ".md") {
+			index[strings.TrimSuffix(name, ".md")] = append(index[strings.TrimSuffix(name, ".md")], relPath)
+		}
+		return nil
+	})
+	return index
+}
+
+// resolveWikilink finds the file a wikilink target names. Obsidian matches
+// names case-insensitively; a target with folders must match the end of
+// the path.
+func (index linkIndex) resolveWikilink(target string) string {
+	target = strings.ToLower(filepath.ToSlash(target))
+	candidates := index[strings.ToLower(filepath.Base(target))]
+	if !strings.Contains(target, "/") {
+		if len(candidates) > 0 {
+			return candidates[0]
+		}
+		return ""
+	}
+
+	for _, candidate := range candidates {
+		path := strings.ToLower(filepath.ToSlash(candidate))
+		if path == target || path == target+".md" ||
+			strings.HasSuffix(path, "/"+target) || strings.HasSuffix(path, "/"+target+".md") {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// resolveMarkdownLink resolves a URL-encoded markdown link relative to the
+// note's folder, falling back to the vault root as Obsidian does
+func (v *ObsidianVault) resolveMarkdownLink(noteDir, target string) string {
+	if decoded, err := url.PathUnescape(target); err == nil {
+		target = decoded
+	}
+
+	for _, base := range []string{noteDir, "."} {
+		relPath := filepath.Clean(filepath.Join(base, target))
+		if strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		for _, candidate := range []string{relPath, relPath + ".md"} {
+			if info, err := os.Stat(filepath.Join(v.Path, candidate)); err == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -41,12 +46,41 @@ var (
 	toolCallStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#50FA7B")).
 			Italic(true)
+
+	messageSourceStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#666666")).
+				MarginLeft(3)
+
+	messageTimeStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#666666"))
 )
 
+// RelativeTimeRefresh is how often the "2m ago" timestamps are redrawn
+const RelativeTimeRefresh = 30 * time.Second
+
+// DefaultMaxToolRounds bounds how many times the model may call tools before answering
+const DefaultMaxToolRounds = 5
+
 // Message represents a chat message
 type Message struct {
 	Role    string
 	Content string
+
+	// Model and Provider record who produced an assistant message
+	Model    string
+	Provider string
+
+	// Time is when the message was added to the chat
+	Time time.Time
+}
+
+// clockTickMsg redraws the relative message timestamps
+type clockTickMsg time.Time
+
+func clockTick() tea.Cmd {
+	return tea.Tick(RelativeTimeRefresh, func(t time.Time) tea.Msg {
+		return clockTickMsg(t)
+	})
 }
 
 // Model represents the application state
@@ -60,14 +94,83 @@ type model struct {
 	height       int
 	cursorPos    int
 	providerType string
+
+	// Sliding-window limits for context sent to the provider, 0 is unlimited
+	contextMessages int
+	contextTokens   int
+
+	// Max tool-call rounds per user message
+	maxToolRounds int
+
+	// Generation settings applied to the connected provider
+	sampling Sampling
+
+	// Destructive tool calls run without asking when autoApprove is set,
+	// otherwise confirming holds the turn waiting for a y/n answer
+	autoApprove bool
+	confirming  *toolTurn
+
+	// Wikilink and tag autocomplete popup
+	completion completion
+
+	// Sent inputs recalled with Up/Down
+	history inputHistory
+
+	// Provider request/response log, nil unless AI_DEBUG=1
+	logger *slog.Logger
+
+	// Embedding backend handed to every loaded vault, nil if not configured
+	embedder Embedder
+
+	// Set when the last turn ended in an error, enables Ctrl+R
+	lastTurnFailed bool
+
+	// Startup configuration, consulted again when connecting to a provider
+	config Config
+
+	// Sent ahead of the conversation when configured, replacing the greeting
+	systemPrompt string
+
+	// Set once /temp or /maxtokens is used, so connecting keeps the session
+	// settings instead of the configured per-provider ones
+	samplingChanged bool
+
+	// Show tool results as raw JSON instead of one-line summaries (/rawtools)
+	rawToolResults bool
+
+	// Estimated spend shown in the header. turnPriced is false when the last
+	// turn's model has no price; sessionCost sums the turns that had one.
+	pricing       Pricing
+	turnCost      float64
+	turnPriced    bool
+	sessionCost   float64
+	sessionPriced bool
+
+	// Provider and /model choices remembered between runs, written to
+	// statePath whenever they change and on quit
+	state     State
+	statePath string
 }
 
-// Initial model
-func initialModel(vaultPath string) model {
-	vault, err := NewObsidianVault(vaultPath)
+// initialModel builds the starting state, restoring the provider and
+// models saved in statePath unless keepProvider (a -provider flag) is set
+func initialModel(cfg Config, statePath string, keepProvider bool) model {
+	state := LoadState(statePath)
+	state.Apply(&cfg, keepProvider)
+
+	messages := []Message{{Role: "system", Content: "AI Agent ready. Provider: Not connected", Time: time.Now()}}
+
+	// The alt screen hides stdout, so a vault failure is shown in the chat
+	vault, err := NewObsidianVault(cfg.VaultPath)
 	if err != nil {
-		fmt.Printf("Warning: Could not load vault: %v\n", err)
+		messages = append(messages, Message{
+			Role:    "system",
+			Time:    time.Now(),
+			Content: fmt.Sprintf("⚠️ Could not load vault: %v. Obsidian tools are unavailable, use /vault <path> to load one.", err),
+		})
 		vault = nil
+	} else {
+		vault.ReadOnly = os.Getenv("OBSIDIAN_READONLY") == "1"
 	}
 
 	tools := NewToolRegistry()
@@ -75,19 +178,65 @@ func initialModel(vaultPath string) model {
 		RegisterObsidianTools(tools, vault)
 	}
 
+	logger, err := NewDebugLogger()
+	if err != nil {
+		messages = append(messages, Message{
+			Role:    "system",
+			Time:    time.Now(),
+			Content: fmt.Sprintf("⚠️ Could not open debug log: %v", err),
+		})
+	}
+	embedder, err := NewEmbedderFromEnv(logger)
+	if err != nil {
+		messages = append(messages, Message{
+			Role:    "system",
+			Time:    time.Now(),
+			Content: fmt.Sprintf("⚠️ Semantic search disabled: %v", err),
+		})
+	}
+	if vault != nil {
+		vault.Logger = logger
+		vault.Embedder = embedder
+	}
+	tools.Logger = logger
+
+	systemPrompt, err := cfg.loadSystemPrompt()
+	if err != nil {
+		// The alt screen hides stdout, so say it in the conversation
+		messages = append(messages, Message{
+			Role:    "system",
+			Time:    time.Now(),
+			Content: fmt.Sprintf("⚠️ Could not load system prompt: %v", err),
+		})
+	}
+
 	return model{
-		messages:     []Message{{Role: "system", Content: "AI Agent ready. Provider: Not connected"}},
+		messages:     messages,
 		input:        "",
 		provider:     nil,
 		tools:        tools,
 		vault:        vault,
-		providerType: "openai",
+		providerType: cfg.Provider,
+
+		contextMessages: envInt("AI_CONTEXT_MESSAGES", DefaultContextMessages),
+		contextTokens:   envInt("AI_CONTEXT_TOKENS", DefaultContextTokens),
+		maxToolRounds:   envInt("AI_MAX_TOOL_ROUNDS", DefaultMaxToolRounds),
+		sampling:        cfg.Sampling(cfg.Provider),
+		autoApprove:     os.Getenv("AI_AUTO_APPROVE") == "1",
+		completion:      completion{dismissedAt: -1},
+		logger:          logger,
+		embedder:        embedder,
+		config:          cfg,
+		systemPrompt:    systemPrompt,
+		pricing:         mergePricing(cfg.Pricing),
+		state:           state,
+		statePath:       statePath,
 	}
 }
 
 // Init initializes the application
 func (m model) Init() tea.Cmd {
-	return nil
+	return clockTick()
 }
 
 // Update handles messages
@@ -98,87 +247,265 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case clockTickMsg:
+		return m, clockTick()
+
 	case tea.KeyMsg:
+		// While a destructive tool call awaits confirmation only y/n are handled
+		if m.confirming != nil && msg.String() != "ctrl+c" {
+			switch msg.String() {
+			case "y", "Y":
+				return m.answerConfirm(true)
+			case "n", "N", "esc":
+				return m.answerConfirm(false)
+			}
+			return m, nil
+		}
+
+		// Autocomplete popup navigation
+		if m.completionVisible() {
+			switch msg.String() {
+			case "up":
+				m.moveCompletion(-1)
+				return m, nil
+			case "down":
+				m.moveCompletion(1)
+				return m, nil
+			case "tab":
+				m.acceptCompletion()
+				return m, nil
+			case "esc":
+				m.dismissCompletion()
+				return m, nil
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "esc":
+			if err := m.saveState(); err != nil && m.logger != nil {
+				m.logger.Warn("saving state failed", "path", m.statePath, "error", err)
+			}
 			return m, tea.Quit
 
 		case "ctrl+p":
 			// Cycle through providers
-			switch m.providerType {
-			case "openai":
-				m.providerType = "anthropic"
-			case "anthropic":
-				m.providerType = "ollama"
-			case "ollama":
-				m.providerType = "openai"
+			for i, p := range ProviderTypes {
+				if p == m.providerType {
+					m.providerType = ProviderTypes[(i+1)%len(ProviderTypes)]
+					break
+				}
 			}
 			m.messages = append(m.messages, Message{
 				Role:    "system",
+				Time:    time.Now(),
 				Content: fmt.Sprintf("Switched to provider: %s", m.providerType),
 			})
+			if err := m.saveState(); err != nil {
+				m.addSystemMessage(fmt.Sprintf("⚠️ Could not save provider selection: %v", err))
+			}
 			return m, nil
 
 		case "ctrl+n":
 			// Connect to provider
-			provider, err := CreateProvider(m.providerType)
+			if !m.samplingChanged {
+				m.sampling = m.config.Sampling(m.providerType)
+			}
+			provider, err := CreateProvider(m.providerType,
+				WithSampling(m.sampling),
+				WithModel(m.config.Model(m.providerType)),
+				WithLogger(m.logger))
 			if err != nil {
 				m.messages = append(m.messages, Message{
 					Role:    "system",
+					Time:    time.Now(),
 					Content: fmt.Sprintf("Error connecting: %v", err),
 				})
+				return m, nil
+			}
+			m.addSystemMessage(fmt.Sprintf("Checking %s credentials...", m.providerType))
+			return m, connectProvider(m.providerType, provider)
+
+		case "ctrl+y":
+			// Copy the raw content of the last assistant reply
+			content, ok := m.lastAssistantReply()
+			if !ok {
+				m.messages = append(m.messages, Message{
+					Role:    "system",
+					Time:    time.Now(),
+					Content: "Nothing to copy yet",
+				})
+			} else if err := clipboard.WriteAll(content); err != nil {
+				m.messages = append(m.messages, Message{
+					Role:    "system",
+					Time:    time.Now(),
+					Content: fmt.Sprintf("Error copying to clipboard: %v", err),
+				})
 			} else {
-				m.provider = provider
 				m.messages = append(m.messages, Message{
 					Role:    "system",
-					Content: fmt.Sprintf("Connected to %s", m.providerType),
+					Time:    time.Now(),
+					Content: "📋 Copied last reply to clipboard",
 				})
 			}
 			return m, nil
 
+		case "ctrl+r":
+			// Re-send the conversation ending in the user message that failed
+			if !m.lastTurnFailed || !m.endsWithUserMessage() {
+				m.addSystemMessage("Nothing to retry")
+				return m, nil
+			}
+			m.lastTurnFailed = false
+			m.addSystemMessage("🔁 Retrying…")
+			return m, m.sendMessage()
+
+		case "up":
+			if input, ok := m.history.prev(m.input); ok {
+				m.input = input
+				cmd := m.updateCompletions()
+				return m, cmd
+			}
+
+		case "down":
+			if input, ok := m.history.next(); ok {
+				m.input = input
+				cmd := m.updateCompletions()
+				return m, cmd
+			}
+
 		case "enter":
 			if m.input == "" {
 				return m, nil
 			}
+			m.history.add(m.input)
+			if isCommand(m.input) {
+				return m.handleCommand()
+			}
+			m.messages = append(m.messages, Message{
+				Role:    "user",
+				Time:    time.Now(),
+				Content: m.input,
+			})
+			m.input = ""
+			m.lastTurnFailed = false
+			m.updateCompletions()
 			return m, m.sendMessage()
 
 		case "backspace":
 			if len(m.input) > 0 {
 				m.input = m.input[:len(m.input)-1]
 			}
+			cmd := m.updateCompletions()
+			return m, cmd
 
 		default:
 			if msg.Type == tea.KeyRunes {
 				m.input += string(msg.Runes)
+				cmd := m.updateCompletions()
+				return m, cmd
 			}
 		}
 
+	case completionCandidatesMsg:
+		m.setCompletionCandidates(msg)
+
+	case connectMsg:
+		if msg.err != nil {
+			m.addSystemMessage(fmt.Sprintf("Error connecting to %s: %v", msg.providerType, msg.err))
+			return m, nil
+		}
+		m.provider = msg.provider
+		m.addSystemMessage(fmt.Sprintf("Connected to %s", msg.providerType))
+
+	case toolRequestMsg:
+		return m.confirmNext(msg.turn)
+
+	case toolsDoneMsg:
+		// Show the round that just ran while the model works on the next step
+		rounds := msg.turn.toolRounds
+		round := rounds[len(rounds)-1]
+		lines := make([]string, len(round))
+		for i, tc := range round {
+			lines[i] = describeToolCall(tc, m.rawToolResults)
+		}
+		m.messages = append(m.messages, Message{
+			Role:    "system",
+			Time:    time.Now(),
+			Content: strings.Join(lines, "\n"),
+		})
+		return m, m.chatStep(msg.turn)
+
 	case responseMsg:
+		if msg.trimmed > 0 {
+			m.addSystemMessage(fmt.Sprintf("✂️ %d older messages left out of context", msg.trimmed))
+		}
 		m.messages = append(m.messages, Message{
-			Role:    "assistant",
-			Content: msg.content,
+			Role:     "assistant",
+			Time:     time.Now(),
+			Content:  msg.content,
+			Model:    msg.model,
+			Provider: msg.provider,
 		})
-		if len(msg.toolCalls) > 0 {
-			toolNames := make([]string, len(msg.toolCalls))
-			for i, tc := range msg.toolCalls {
-				toolNames[i] = tc.Name
-			}
+		m.turnCost, m.turnPriced = m.pricing.Cost(msg.provider, msg.model, msg.usage)
+		if m.turnPriced {
+			m.sessionCost += m.turnCost
+			m.sessionPriced = true
+		}
+		if msg.toolCapReached {
+			m.addSystemMessage(fmt.Sprintf("⚠️ Stopped after %d tool rounds, the answer may be incomplete", len(msg.toolRounds)))
+		}
+
+	case errorMsg:
+		m.lastTurnFailed = true
+		switch {
+		case errors.Is(msg.err, ErrUnauthorized):
+			m.addSystemMessage("Error: invalid API key or missing permissions")
+		case errors.Is(msg.err, ErrRateLimited):
+			m.addSystemMessage("Error: rate limited by the provider, try again shortly")
+		default:
 			m.messages = append(m.messages, Message{
 				Role:    "system",
-				Content: fmt.Sprintf("🔧 Tools used: %s", strings.Join(toolNames, ", ")),
+				Time:    time.Now(),
+				Content: fmt.Sprintf("Error: %v", msg.err),
 			})
 		}
-
-	case errorMsg:
-		m.messages = append(m.messages, Message{
-			Role:    "system",
-			Content: fmt.Sprintf("Error: %v", msg.err),
-		})
+		m.addSystemMessage("Press Ctrl+R to retry")
 	}
 
 	return m, nil
 }
 
+// messageSource labels an assistant message with the model and provider
+// that produced it, e.g. "gpt-4o · openai"
+func messageSource(msg Message) string {
+	var parts []string
+	if msg.Model != "" {
+		parts = append(parts, msg.Model)
+	}
+	if msg.Provider != "" {
+		parts = append(parts, msg.Provider)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// relativeTime describes t as seen from now: "just now", "5m ago", "3h ago"
+// or "2d ago". A zero t is empty.
+func relativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch age := now.Sub(t); {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age/time.Minute))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(age/(24*time.Hour)))
+	}
+}
+
 // View renders the UI
 func (m model) View() string {
 	var b strings.Builder
@@ -186,34 +513,59 @@ func (m model) View() string {
 	// Header
 	b.WriteString(titleStyle.Render("🤖 AI Agent - Obsidian Assistant"))
 	b.WriteString("\n")
-	b.WriteString(systemMessageStyle.Render(fmt.Sprintf("Provider: %s | Ctrl+P: Switch | Ctrl+N: Connect | Ctrl+C: Quit", m.providerType)))
+	b.WriteString(systemMessageStyle.Render(fmt.Sprintf("Provider: %s | 🔧 %d tools | 💰 turn %s, session %s | Ctrl+P: Switch | Ctrl+N: Connect | Ctrl+Y: Copy | Ctrl+R: Retry | Ctrl+C: Quit",
+		m.providerType, m.tools.Len(), formatCost(m.turnCost, m.turnPriced), formatCost(m.sessionCost, m.sessionPriced))))
 	b.WriteString("\n\n")
 
 	// Messages
-	chatHeight := m.height - 8
+	chatHeight := m.height - 8 - len(m.completion.items)
 	visibleMessages := m.messages
 	if len(visibleMessages) > chatHeight {
 		visibleMessages = visibleMessages[len(visibleMessages)-chatHeight:]
 	}
 
+	// User messages stay clean, the reply below them carries the time
+	now := time.Now()
 	for _, msg := range visibleMessages {
+		age := relativeTime(msg.Time, now)
 		switch msg.Role {
 		case "user":
 			b.WriteString(userMessageStyle.Render("You: " + msg.Content))
 		case "assistant":
 			b.WriteString(assistantMessageStyle.Render("AI: " + msg.Content))
+			label := messageSource(msg)
+			if label != "" && age != "" {
+				label += " · "
+			}
+			if label += age; label != "" {
+				b.WriteString("\n")
+				b.WriteString(messageSourceStyle.Render(label))
+			}
 		case "system":
 			if strings.Contains(msg.Content, "🔧") {
 				b.WriteString(toolCallStyle.Render(msg.Content))
 			} else {
 				b.WriteString(systemMessageStyle.Render(msg.Content))
 			}
+			if age != "" {
+				b.WriteString(messageTimeStyle.Render(" · " + age))
+			}
 		}
 		b.WriteString("\n")
 	}
 
-	// Input
+	// Autocomplete suggestions
 	b.WriteString("\n")
+	for i, item := range m.completion.items {
+		if i == m.completion.selected {
+			b.WriteString(inputStyle.Render("▸ " + item))
+		} else {
+			b.WriteString(systemMessageStyle.Render("  " + item))
+		}
+		b.WriteString("\n")
+	}
+
+	// Input
 	b.WriteString(strings.Repeat("─", m.width))
 	b.WriteString("\n")
 	b.WriteString(inputStyle.Render("> " + m.input + "▊"))
@@ -221,107 +573,133 @@ func (m model) View() string {
 	return b.String()
 }
 
+// endsWithUserMessage reports whether the last non-system message is from the user
+func (m model) endsWithUserMessage() bool {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role != "system" {
+			return m.messages[i].Role == "user"
+		}
+	}
+	return false
+}
+
+// lastAssistantReply returns the content of the most recent assistant message
+func (m model) lastAssistantReply() (string, bool) {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == "assistant" {
+			return m.messages[i].Content, true
+		}
+	}
+	return "", false
+}
+
 // Message types
 type responseMsg struct {
-	content   string
-	toolCalls []ToolCall
+	content        string
+	model          string
+	provider       string
+	usage          Usage
+	toolRounds     [][]ToolCall
+	toolCapReached bool
+	trimmed        int
 }
 
 type errorMsg struct {
 	err error
 }
 
-// sendMessage sends a message to the AI
-func (m model) sendMessage() tea.Cmd {
-	userMsg := m.input
-	m.input = ""
-
-	m.messages = append(m.messages, Message{
-		Role:    "user",
-		Content: userMsg,
-	})
+// connectMsg carries the result of pinging a newly created provider
+type connectMsg struct {
+	providerType string
+	provider     Provider
+	err          error
+}
 
+// connectProvider checks the provider's credentials in the background
+func connectProvider(providerType string, provider Provider) tea.Cmd {
 	return func() tea.Msg {
-		if m.provider == nil {
-			return errorMsg{err: fmt.Errorf("not connected to provider")}
-		}
-
-		ctx := context.Background()
-
-		// Convert messages
-		chatMessages := make([]ChatMessage, 0, len(m.messages))
-		for _, msg := range m.messages {
-			if msg.Role != "system" || strings.Contains(msg.Content, "AI Agent ready") {
-				chatMessages = append(chatMessages, ChatMessage{
-					Role:    msg.Role,
-					Content: msg.Content,
-				})
-			}
+		ctx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+		defer cancel()
+		return connectMsg{
+			providerType: providerType,
+			provider:     provider,
+			err:          provider.Ping(ctx),
 		}
+	}
+}
 
-		// Get tool definitions
-		var tools []Tool
-		if m.tools != nil {
-			tools = m.tools.GetToolDefinitions()
+// sendMessage sends the conversation, ending in the latest user message, to the AI
+func (m model) sendMessage() tea.Cmd {
+	if m.provider == nil {
+		return func() tea.Msg {
+			return errorMsg{err: fmt.Errorf("not connected to provider")}
 		}
+	}
 
-		// Call provider
-		response, err := m.provider.Chat(ctx, chatMessages, tools)
-		if err != nil {
-			return errorMsg{err: err}
+	// Convert messages
+	chatMessages := make([]ChatMessage, 0, len(m.messages)+1)
+	if m.systemPrompt != "" {
+		chatMessages = append(chatMessages, ChatMessage{Role: "system", Content: m.systemPrompt})
+	}
+	for _, msg := range m.messages {
+		greeting := m.systemPrompt == "" && strings.Contains(msg.Content, "AI Agent ready")
+		if msg.Role != "system" || greeting {
+			chatMessages = append(chatMessages, ChatMessage{
+				Role:    msg.Role,
+				Content: msg.Content,
+			})
 		}
+	}
 
-		// Execute tools if needed
-		if len(response.ToolCalls) > 0 && m.tools != nil {
-			for i := range response.ToolCalls {
-				result, err := m.tools.ExecuteTool(
-					response.ToolCalls[i].Name,
-					response.ToolCalls[i].Arguments,
-				)
-				if err != nil {
-					response.ToolCalls[i].Result = fmt.Sprintf("Error: %v", err)
-				} else {
-					resultJSON, _ := json.Marshal(result)
-					response.ToolCalls[i].Result = string(resultJSON)
-				}
-			}
-
-			// Get final response after tool execution
-			toolMessages := append(chatMessages, ChatMessage{
-				Role:      "assistant",
-				Content:   response.Content,
-				ToolCalls: response.ToolCalls,
-			})
+	chatMessages, trimmed := trimContext(chatMessages, m.contextMessages, m.contextTokens)
 
-			for _, tc := range response.ToolCalls {
-				toolMessages = append(toolMessages, ChatMessage{
-					Role:       "tool",
-					Content:    tc.Result,
-					ToolCallID: tc.ID,
-				})
-			}
+	// Get tool definitions
+	var tools []Tool
+	if m.tools != nil {
+		tools = m.tools.GetToolDefinitions()
+	}
 
-			finalResponse, err := m.provider.Chat(ctx, toolMessages, nil)
-			if err == nil {
-				response = finalResponse
-			}
-		}
+	return m.chatStep(&toolTurn{
+		chatMessages: chatMessages,
+		tools:        tools,
+		trimmed:      trimmed,
+	})
+}
 
-		return responseMsg{
-			content:   response.Content,
-			toolCalls: response.ToolCalls,
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
 		}
 	}
+	return def
 }
 
 func main() {
-	vaultPath := os.Getenv("OBSIDIAN_VAULT_PATH")
-	if vaultPath == "" {
-		vaultPath = os.Getenv("HOME") + "/Documents/Obsidian"
+	configPath := flag.String("config", DefaultConfigPath(), "path to config.toml")
+	vaultPath := flag.String("vault", "", "Obsidian vault path (overrides config and OBSIDIAN_VAULT_PATH)")
+	providerType := flag.String("provider", "", "provider selected at startup: "+strings.Join(ProviderTypes, ", "))
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *vaultPath != "" {
+		cfg.VaultPath = *vaultPath
+	}
+	if *providerType != "" {
+		cfg.Provider = *providerType
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
 
 	p := tea.NewProgram(
-		initialModel(vaultPath),
+		initialModel(cfg, DefaultStatePath(), *providerType != ""),
 		tea.WithAltScreen(),
 	)
 
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MoveResult reports a MoveNote. Notes whose links to the moved note were
+// rewritten are in UpdatedNotes; without updateLinks the notes whose links
+// no longer resolve are in StaleNotes instead.
+type MoveResult struct {
+	From         string   `json:"from"`
+	To           string   `json:"to"`
+	UpdatedNotes []string `json:"updated_notes,omitempty"`
+	StaleNotes   []string `json:"stale_notes,omitempty"`
+}
+
+// MoveNote moves or renames the note at from to to, creating the folders
+// on the way. A to ending in a slash, or naming an existing folder, keeps
+// the file name. The note itself is moved as-is, byte for byte; an existing
+// note at to is never overwritten. With updateLinks, links in other notes
+// that pointed at from are rewritten to keep pointing at the note.
+func (v *ObsidianVault) MoveNote(from, to string, updateLinks bool) (*MoveResult, error) {
+	fromPath, err := v.resolveInVault(from)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(fromPath); err != nil || info.IsDir() || !strings.HasSuffix(fromPath, ".md") {
+		return nil, fmt.Errorf("note not found: %s", from)
+	}
+
+	if info, err := os.Stat(filepath.Join(v.Path, to)); strings.HasSuffix(to, "/") || (err == nil && info.IsDir()) {
+		to = filepath.Join(to, filepath.Base(fromPath))
+	} else if !strings.HasSuffix(to, ".md") {
+		to += ".md"
+	}
+	toPath, err := v.resolveInVault(to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MoveResult{}
+	result.From, _ = filepath.Rel(v.Path, fromPath)
+	result.To, _ = filepath.Rel(v.Path, toPath)
+	if result.From == result.To {
+		return nil, fmt.Errorf("note is already at %s", result.To)
+	}
+	if _, err := os.Lstat(toPath); !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", errNoteExists, result.To)
+	}
+
+	// Links are resolved against the vault as it is before the move
+	rewrites := v.linkRewrites(result.From, result.To)
+	linking := make([]string, 0, len(rewrites))
+	for notePath := range rewrites {
+		linking = append(linking, notePath)
+	}
+	sort.Strings(linking)
+	if updateLinks {
+		result.UpdatedNotes = linking
+	} else {
+		result.StaleNotes = linking
+	}
+
+	if v.ReadOnly {
+		return result, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := moveFile(fromPath, toPath); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("%w: %s", errNoteExists, result.To)
+		}
+		return nil, err
+	}
+	v.invalidateNote(result.From)
+	v.invalidateNote(result.To)
+	v.audit("move", result.From, "-> "+result.To)
+
+	if !updateLinks {
+		return result, nil
+	}
+	for _, notePath := range result.UpdatedNotes {
+		rewrite := rewrites[notePath]
+		if err := os.WriteFile(filepath.Join(v.Path, notePath), []byte(rewrite.content), 0644); err != nil {
+			return result, fmt.Errorf("note moved, but updating links in %s failed: %w", notePath, err)
+		}
+		v.invalidateNote(notePath)
+		v.audit("update", notePath, fmt.Sprintf("%d links -> %s", rewrite.links, result.To))
+	}
+	return result, nil
+}
+
+// moveFile renames from to to without replacing an existing file. The hard
+// link fails if to exists; filesystems without hard links fall back to a
+// plain rename.
+func moveFile(from, to string) error {
+	err := os.Link(from, to)
+	if errors.Is(err, fs.ErrExist) {
+		return err
+	} else if err != nil {
+		return os.Rename(from, to)
+	}
+	return os.Remove(from)
+}
+
+// linkRewrite is the new content of a note linking to a moved note
+type linkRewrite struct {
+	content string
+	links   int
+}
+
+// linkRewrites returns, by note path, the content other notes get when their
+// links to the note at from are changed to point at to. Wikilinks keep the
+// bare note name when it stays unambiguous and otherwise get the vault path;
+// markdown links get the new path relative to the linking note. Anchors,
+// aliases and fenced code blocks are left alone, as is the moved note.
+func (v *ObsidianVault) linkRewrites(from, to string) map[string]linkRewrite {
+	index := v.linkIndex()
+
+	// Whether the new name alone will still name only the moved note
+	newName := strings.TrimSuffix(filepath.Base(to), ".md")
+	nameUnique := true
+	for _, candidate := range index[strings.ToLower(newName)] {
+		if candidate != from {
+			nameUnique = false
+		}
+	}
+
+	newWikiTarget := func(target string) string {
+		hasFolder := strings.Contains(filepath.ToSlash(target), "/")
+		name := strings.TrimSuffix(target, ".md")
+		if !hasFolder && nameUnique && strings.EqualFold(name, newName) {
+			return target
+		}
+		newTarget := newName
+		if hasFolder || !nameUnique {
+			newTarget = strings.TrimSuffix(filepath.ToSlash(to), ".md")
+		}
+		if name != target {
+			newTarget += ".md"
+		}
+		return newTarget
+	}
+
+	rewrites := make(map[string]linkRewrite)
+	filepath.Walk(v.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != v.Path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		notePath, _ := filepath.Rel(v.Path, path)
+		if notePath == from {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		noteDir := filepath.Dir(notePath)
+		changed := 0
+		lines := strings.Split(string(content), "\n")
+		inFence := false
+		for i, line := range lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inFence = !inFence
+				continue
+			}
+			if inFence {
+				continue
+			}
+
+			line = replaceSubmatch(line, wikiLinkPattern, 2, func(inner string) string {
+				rawTarget := inner
+				if end := strings.IndexAny(inner, "#|"); end >= 0 {
+					rawTarget = inner[:end]
+				}
+				target := strings.TrimSpace(rawTarget)
+				if target == "" || index.resolveWikilink(target) != from {
+					return inner
+				}
+				newTarget := newWikiTarget(target)
+				if newTarget == target {
+					return inner
+				}
+				changed++
+				return strings.Replace(inner, target, newTarget, 1)
+			})
+
+			line = replaceSubmatch(line, markdownLinkPattern, 3, func(rawTarget string) string {
+				if externalLinkPattern.MatchString(rawTarget) {
+					return rawTarget
+				}
+				target, anchor, hasAnchor := strings.Cut(rawTarget, "#")
+				if target == "" || v.resolveMarkdownLink(noteDir, target) != from {
+					return rawTarget
+				}
+				relPath, err := filepath.Rel(noteDir, to)
+				if err != nil {
+					return rawTarget
+				}
+				changed++
+				newTarget := (&url.URL{Path: filepath.ToSlash(relPath)}).EscapedPath()
+				if hasAnchor {
+					newTarget += "#" + anchor
+				}
+				return newTarget
+			})
+			lines[i] = line
+		}
+
+		if changed > 0 {
+			rewrites[notePath] = linkRewrite{content: strings.Join(lines, "\n"), links: changed}
+		}
+		return nil
+	})
+	return rewrites
+}
+
+// replaceSubmatch replaces submatch group of every pattern match in s
+// with what replace returns for it
+func replaceSubmatch(s string, pattern *regexp.Regexp, group int, replace func(string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := loc[2*group], loc[2*group+1]
+		if start < 0 {
+			continue
+		}
+		out.WriteString(s[last:start])
+		out.WriteString(replace(s[start:end]))
+		last = end
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// noteIndexEntry is what the vault knows about one note as of its last
+// scan. Entries are replaced, never modified, so they can be shared.
+type noteIndexEntry struct {
+	modTime time.Time
+	size    int64
+	hash    string         // contentHash of the file, frontmatter included
+	tags    map[string]int // hashtag counts
+	words   int            // in the body, frontmatter excluded
+	links   []Link         // outgoing, unresolved
+	aliases []string       // from the frontmatter
+}
+
+// fresh reports whether the entry still describes a file with this
+// modification time and size
+func (e noteIndexEntry) fresh(modTime time.Time, size int64) bool {
+	return e.modTime.Equal(modTime) && e.size == size
+}
+
+// contentHash identifies a note's content, the key for everything derived
+// from it: modification times change on a touch and can't be trusted
+// across clock skew
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newNoteIndexEntry(info os.FileInfo, data []byte, hash string) noteIndexEntry {
+	content := string(data)
+	entry := noteIndexEntry{modTime: info.ModTime(), size: info.Size(), hash: hash, tags: map[string]int{}}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		if len(match) > 1 {
+			entry.tags[match[1]]++
+		}
+	}
+	_, body, _ := splitFrontmatter(content)
+	entry.words = len(strings.Fields(body))
+	entry.links = parseLinks(content)
+	entry.aliases = noteAliases(content)
+	return entry
+}
+
+// scanIndex brings the note index up to date, reading only notes whose
+// modification time or size changed and reparsing only those whose content
+// hash changed, and returns a snapshot of it keyed by vault-relative path
+func (v *ObsidianVault) scanIndex() (map[string]noteIndexEntry, error) {
+	v.indexMu.Lock()
+	defer v.indexMu.Unlock()
+
+	if v.noteIndex == nil {
+		v.noteIndex = make(map[string]noteIndexEntry)
+	}
+
+	seen := make(map[string]bool)
+	err := filepath.Walk(v.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, ".md") {
+			relPath, _ := filepath.Rel(v.Path, path)
+			entry, ok := v.noteIndex[relPath]
+			if !ok || !entry.fresh(info.ModTime(), info.Size()) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return nil
+				}
+				if hash := contentHash(content); ok && entry.hash == hash {
+					// Touched but not edited, the parsed data still holds
+					entry.modTime, entry.size = info.ModTime(), info.Size()
+					v.noteIndex[relPath] = entry
+				} else {
+					v.noteIndex[relPath] = newNoteIndexEntry(info, content, hash)
+				}
+			}
+			seen[relPath] = true
+		}
+		return nil
+	})
+
+	// Forget notes that were deleted or moved
+	for path := range v.noteIndex {
+		if !seen[path] {
+			delete(v.noteIndex, path)
+		}
+	}
+
+	snapshot := make(map[string]noteIndexEntry, len(v.noteIndex))
+	for path, entry := range v.noteIndex {
+		snapshot[path] = entry
+	}
+	return snapshot, err
+}
+
+// invalidateNote drops the indexed entry of a note the vault just wrote, so
+// the next scan rereads it even if its modification time and size didn't
+// change
+func (v *ObsidianVault) invalidateNote(notePath string) {
+	v.indexMu.Lock()
+	defer v.indexMu.Unlock()
+
+	delete(v.noteIndex, filepath.Clean(notePath))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// benchVault creates a vault of n tagged notes of a few kilobytes each
+func benchVault(b *testing.B, n int) *ObsidianVault {
+	b.Helper()
+	dir := b.TempDir()
+	body := strings.Repeat("Some words about the project and #topic/sub notes.\n", 60)
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("# Note %d\n#tag%d #shared\n%s", i, i%50, body)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("note%04d.md", i)), []byte(content), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	vault, err := NewObsidianVault(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return vault
+}
+
+// BenchmarkGetTagsUncached rescans every note, as GetTags did before the index
+func BenchmarkGetTagsUncached(b *testing.B) {
+	vault := benchVault(b, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vault.noteIndex = nil
+		if _, err := vault.GetTags(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetTagsCached repeats GetTags on an unchanged vault, only
+// stat-ing the notes
+func BenchmarkGetTagsCached(b *testing.B) {
+	vault := benchVault(b, 500)
+	if _, err := vault.GetTags(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vault.GetTags(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetTagsFollowsVaultChanges(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"a.md": "#go #cli",
+		"b.md": "#go",
+	})
+
+	tags, err := vault.GetTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags["go"] != 2 || tags["cli"] != 1 {
+		t.Fatalf("tags = %v", tags)
+	}
+
+	// Same size and, within the filesystem's resolution, same modification
+	// time: only the invalidation makes the index reread it
+	if err := vault.UpdateNote("a.md", "#rs #cli", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(vault.Path, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err = vault.GetTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags["go"] != 0 || tags["rs"] != 1 || tags["cli"] != 1 {
+		t.Errorf("tags after the edit and delete = %v, want rs and cli only", tags)
+	}
+}
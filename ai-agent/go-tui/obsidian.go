@@ -1,39 +1,106 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 // ObsidianVault represents an Obsidian vault
 type ObsidianVault struct {
 	Path string
+
+	// ReadOnly makes write operations report what they would do without touching disk
+	ReadOnly bool
+
+	// Logger receives audit log write failures, nil uses slog.Default
+	Logger *slog.Logger
+
+	// Embedder backs semantic search, nil disables it
+	Embedder Embedder
+
+	// TemplatesFolder holds the note templates, relative to the vault root.
+	// Empty means OBSIDIAN_TEMPLATES_FOLDER or DefaultTemplatesFolder.
+	TemplatesFolder string
+
+	// MaxAttachmentBytes caps ReadAttachment. Zero means
+	// OBSIDIAN_MAX_ATTACHMENT_BYTES or DefaultMaxAttachmentBytes.
+	MaxAttachmentBytes int64
+
+	// AnyAttachmentType lets ReadAttachment return files that aren't images,
+	// as does OBSIDIAN_ANY_ATTACHMENT=1
+	AnyAttachmentType bool
+
+	// HighlightMarker wraps matches in highlighted search previews. Empty
+	// means OBSIDIAN_HIGHLIGHT_MARKER or DefaultHighlightMarker.
+	HighlightMarker string
+
+	// In-memory copy of EmbeddingCacheFile, loaded on first search
+	embedMu    sync.Mutex
+	embeddings *embeddingCache
+
+	// Per-note tags, word counts and links by note path, rescanned when a
+	// note changes, see scanIndex
+	indexMu   sync.Mutex
+	noteIndex map[string]noteIndexEntry
+}
+
+// DefaultHighlightMarker is Obsidian's own highlight syntax
+const DefaultHighlightMarker = "=="
+
+// MaxPreviewBytes caps a search result preview
+const MaxPreviewBytes = 200
+
+// Match is a search hit in a preview, as [Start, End) character (rune)
+// offsets so they index the same text in any language
+type Match struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// MaxMatchesPerNote caps the matching lines SearchNotes lists per note
+const MaxMatchesPerNote = 20
+
+// MatchInfo is a line of a note holding a search hit, numbered from 1 over
+// the raw file (frontmatter included) as read_lines counts them. Offset is
+// the byte offset of the line's first hit in the file.
+type MatchInfo struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Offset int    `json:"offset"`
 }
 
 // NoteInfo contains information about a note
 type NoteInfo struct {
-	Path     string    `json:"path"`
-	Title    string    `json:"title"`
-	Size     int64     `json:"size,omitempty"`
-	Modified time.Time `json:"modified,omitempty"`
-	Preview  string    `json:"preview,omitempty"`
-	Content  string    `json:"content,omitempty"`
+	Path           string      `json:"path"`
+	Title          string      `json:"title"`
+	Size           int64       `json:"size,omitempty"`
+	Modified       time.Time   `json:"modified,omitempty"`
+	Preview        string      `json:"preview,omitempty"`
+	PreviewMatches []Match     `json:"preview_matches,omitempty"` // in Preview
+	Matches        []MatchInfo `json:"matches,omitempty"`         // lines with a hit, set only by SearchNotes
+	Content        string      `json:"content,omitempty"`
+	ContentHash    string      `json:"content_hash,omitempty"` // SHA-256 of the file, changes only with the content
+	Score          float64     `json:"score,omitempty"`
+	Aliases        []string    `json:"aliases,omitempty"`
 }
 
 // NewObsidianVault creates a new Obsidian vault interface
 func NewObsidianVault(path string) (*ObsidianVault, error) {
-	// Expand home directory
-	if strings.HasPrefix(path, "~/") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, err
-		}
-		path = filepath.Join(home, path[2:])
+	path, err := expandPath(path)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if path exists
@@ -44,8 +111,32 @@ func NewObsidianVault(path string) (*ObsidianVault, error) {
 	return &ObsidianVault{Path: path}, nil
 }
 
-// SearchNotes searches for notes containing query
-func (v *ObsidianVault) SearchNotes(query string, caseSensitive bool) ([]NoteInfo, error) {
+// resolveInVault joins a vault-relative path onto the vault root, refusing
+// paths that would end up outside it
+func (v *ObsidianVault) resolveInVault(relPath string) (string, error) {
+	rel, err := filepath.Rel(v.Path, filepath.Join(v.Path, relPath))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path is outside the vault: %s", relPath)
+	}
+	return filepath.Join(v.Path, rel), nil
+}
+
+// SearchNotes searches for notes containing query, in the whole vault or
+// only under folder. Each preview lists the match offsets, or with
+// highlight has the matches wrapped in the highlight marker instead. With
+// plain the preview is built from the body's lines as plain text, without
+// frontmatter or markdown syntax; otherwise it holds the raw lines. Aliases
+// lists the note's frontmatter aliases matching query, so a note found by
+// an alias says so even when its preview is empty.
+func (v *ObsidianVault) SearchNotes(query string, caseSensitive bool, folder string, highlight, plain bool) ([]NoteInfo, error) {
+	root, err := v.resolveInVault(folder)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("folder not found: %s", folder)
+	}
+
 	var results []NoteInfo
 	flags := 0
 	if !caseSensitive {
@@ -57,7 +148,7 @@ func (v *ObsidianVault) SearchNotes(query string, caseSensitive bool) ([]NoteInf
 		return nil, err
 	}
 
-	err = filepath.Walk(v.Path, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
@@ -70,30 +161,60 @@ func (v *ObsidianVault) SearchNotes(query string, caseSensitive bool) ([]NoteInf
 
 			if pattern.Match(content) {
 				relPath, _ := filepath.Rel(v.Path, path)
-				lines := strings.Split(string(content), "\n")
+				text := string(content)
+				if plain {
+					_, text, _ = splitFrontmatter(text)
+				}
 				preview := ""
 
-				for _, line := range lines {
+				for _, line := range strings.Split(text, "\n") {
+					if plain {
+						line = markdownToPlain(line)
+					}
+					// A note matching only in stripped syntax gets an empty preview
 					if pattern.MatchString(line) {
 						if preview != "" {
 							preview += "\n"
 						}
 						preview += line
-						if len(preview) > 200 {
+						if len(preview) > MaxPreviewBytes {
 							break
 						}
 					}
 				}
 
-				if len(preview) > 200 {
-					preview = preview[:200] + "..."
+				truncated := len(preview) > MaxPreviewBytes
+				if truncated {
+					preview = truncateUTF8(preview, MaxPreviewBytes)
 				}
 
-				results = append(results, NoteInfo{
-					Path:    relPath,
-					Title:   strings.TrimSuffix(info.Name(), ".md"),
-					Preview: preview,
-				})
+				// A match cut by the truncation isn't found, so it isn't reported
+				result := NoteInfo{
+					Path:  relPath,
+					Title: strings.TrimSuffix(info.Name(), ".md"),
+				}
+				if highlight {
+					marker := v.highlightMarker()
+					result.Preview = pattern.ReplaceAllStringFunc(preview, func(match string) string {
+						return marker + match + marker
+					})
+				} else {
+					result.Preview = preview
+					result.PreviewMatches = matchOffsets(pattern, preview)
+				}
+				if truncated {
+					result.Preview += "..."
+				}
+
+				result.Matches = matchLines(pattern, string(content))
+
+				for _, alias := range noteAliases(string(content)) {
+					if pattern.MatchString(alias) {
+						result.Aliases = append(result.Aliases, alias)
+					}
+				}
+
+				results = append(results, result)
 			}
 		}
 		return nil
@@ -102,13 +223,81 @@ func (v *ObsidianVault) SearchNotes(query string, caseSensitive bool) ([]NoteInf
 	return results, err
 }
 
-// ReadNote reads a complete note
+func (v *ObsidianVault) highlightMarker() string {
+	if v.HighlightMarker != "" {
+		return v.HighlightMarker
+	}
+	if marker := os.Getenv("OBSIDIAN_HIGHLIGHT_MARKER"); marker != "" {
+		return marker
+	}
+	return DefaultHighlightMarker
+}
+
+// matchOffsets returns the rune offsets of pattern's matches in text
+func matchOffsets(pattern *regexp.Regexp, text string) []Match {
+	var matches []Match
+	runes, last := 0, 0
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		runes += utf8.RuneCountInString(text[last:loc[0]])
+		start := runes
+		runes += utf8.RuneCountInString(text[loc[0]:loc[1]])
+		matches = append(matches, Match{Start: start, End: runes})
+		last = loc[1]
+	}
+	return matches
+}
+
+// matchLines lists the lines of content holding a pattern match, up to
+// MaxMatchesPerNote, with long lines cut to MaxPreviewBytes
+func matchLines(pattern *regexp.Regexp, content string) []MatchInfo {
+	var matches []MatchInfo
+	offset := 0
+	for i, line := range strings.Split(content, "\n") {
+		if loc := pattern.FindStringIndex(line); loc != nil {
+			matches = append(matches, MatchInfo{
+				Line:   i + 1,
+				Text:   truncateUTF8(strings.TrimSuffix(line, "\r"), MaxPreviewBytes),
+				Offset: offset + loc[0],
+			})
+			if len(matches) == MaxMatchesPerNote {
+				break
+			}
+		}
+		offset += len(line) + 1
+	}
+	return matches
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// ReadNote reads a complete note. notePath may also be one of a note's
+// frontmatter aliases.
 func (v *ObsidianVault) ReadNote(notePath string) (*NoteInfo, error) {
 	fullPath := filepath.Join(v.Path, notePath)
 
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
-		return nil, fmt.Errorf("note not found: %s", notePath)
+		resolved, aliasErr := v.ResolveAlias(strings.TrimSuffix(notePath, ".md"))
+		if errors.Is(aliasErr, errUnknownAlias) {
+			return nil, fmt.Errorf("note not found: %s", notePath)
+		}
+		if aliasErr != nil {
+			return nil, aliasErr
+		}
+
+		notePath, fullPath = resolved, filepath.Join(v.Path, resolved)
+		if content, err = os.ReadFile(fullPath); err != nil {
+			return nil, fmt.Errorf("note not found: %s", notePath)
+		}
 	}
 
 	info, err := os.Stat(fullPath)
@@ -117,16 +306,80 @@ func (v *ObsidianVault) ReadNote(notePath string) (*NoteInfo, error) {
 	}
 
 	return &NoteInfo{
-		Path:     notePath,
-		Title:    strings.TrimSuffix(filepath.Base(notePath), ".md"),
-		Content:  string(content),
-		Size:     info.Size(),
-		Modified: info.ModTime(),
+		Path:        notePath,
+		Title:       strings.TrimSuffix(filepath.Base(notePath), ".md"),
+		Content:     string(content),
+		ContentHash: contentHash(content),
+		Size:        info.Size(),
+		Modified:    info.ModTime(),
+		Aliases:     noteAliases(string(content)),
+	}, nil
+}
+
+// Line ranges returned by ReadLines
+const (
+	DefaultReadLines = 20
+	MaxReadLines     = 200
+)
+
+// NoteLines is a range of a note's lines, numbered from 1, as the lines of
+// a search match are
+type NoteLines struct {
+	Path       string `json:"path"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+	TotalLines int    `json:"total_lines"`
+	Content    string `json:"content"`
+}
+
+// ReadLines reads lines start through end (inclusive) of a note, found as
+// ReadNote finds it. end <= 0 reads DefaultReadLines lines, and a range
+// is capped at MaxReadLines and at the end of the note.
+func (v *ObsidianVault) ReadLines(notePath string, start, end int) (*NoteLines, error) {
+	note, err := v.ReadNote(notePath)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(note.Content, "\n"), "\n")
+	if start < 1 || start > len(lines) {
+		return nil, fmt.Errorf("line %d is outside the note, which has %d lines", start, len(lines))
+	}
+	if end <= 0 {
+		end = start + DefaultReadLines - 1
+	}
+	if end < start {
+		return nil, fmt.Errorf("end line %d is before start line %d", end, start)
+	}
+	end = min(end, start+MaxReadLines-1, len(lines))
+
+	return &NoteLines{
+		Path:       note.Path,
+		Start:      start,
+		End:        end,
+		TotalLines: len(lines),
+		Content:    strings.Join(lines[start-1:end], "\n"),
 	}, nil
 }
 
-// CreateNote creates a new note
-func (v *ObsidianVault) CreateNote(title, content, folder string, tags []string) (string, error) {
+// What CreateNote does when a note with the same file name exists
+const (
+	OnConflictError  = "error"  // refuse, the default
+	OnConflictSuffix = "suffix" // create "Title-1.md", "Title-2.md", ...
+)
+
+// errNoteExists is returned when creating a note would overwrite another
+var errNoteExists = errors.New("note already exists")
+
+// CreateNote creates a new note. An existing note is never overwritten,
+// see OnConflictError and OnConflictSuffix.
+func (v *ObsidianVault) CreateNote(title, content, folder string, tags []string, onConflict string) (string, error) {
+	return v.CreateNoteWithMeta(title, content, folder, tags, nil, onConflict)
+}
+
+// CreateNoteWithMeta creates a new note with extra frontmatter keys
+// (written in sorted order after created)
+func (v *ObsidianVault) CreateNoteWithMeta(title, content, folder string, tags []string, meta map[string]string, onConflict string) (string, error) {
 	// Sanitize filename
 	filename := sanitizeFilename(title)
 	if !strings.HasSuffix(filename, ".md") {
@@ -136,51 +389,221 @@ func (v *ObsidianVault) CreateNote(title, content, folder string, tags []string)
 	targetDir := v.Path
 	if folder != "" {
 		targetDir = filepath.Join(v.Path, folder)
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			return "", err
-		}
 	}
 
-	filePath := filepath.Join(targetDir, filename)
+	filePath, err := freeNotePath(filepath.Join(targetDir, filename), onConflict)
+	if err != nil {
+		relPath, _ := filepath.Rel(v.Path, filePath)
+		return "", fmt.Errorf("%w: %s", err, relPath)
+	}
+	relPath, _ := filepath.Rel(v.Path, filePath)
+
+	if v.ReadOnly {
+		return relPath, nil
+	}
+
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", err
+	}
+
+	// Build frontmatter, merging into the content's own block if it has one
+	existing, body, _ := splitFrontmatter(content)
+	lines, keys, existingTags := parseFrontmatter(existing)
 
-	// Build frontmatter
 	var fullContent strings.Builder
 	fullContent.WriteString("---\n")
-	fullContent.WriteString(fmt.Sprintf("created: %s\n", time.Now().Format(time.RFC3339)))
-	if len(tags) > 0 {
+	if !keys["created"] {
+		fullContent.WriteString(fmt.Sprintf("created: %s\n", time.Now().Format(time.RFC3339)))
+	}
+	for _, line := range lines {
+		fullContent.WriteString(line + "\n")
+	}
+	metaKeys := make([]string, 0, len(meta))
+	for key := range meta {
+		if !keys[key] {
+			metaKeys = append(metaKeys, key)
+		}
+	}
+	sort.Strings(metaKeys)
+	for _, key := range metaKeys {
+		fullContent.WriteString(fmt.Sprintf("%s: %s\n", key, meta[key]))
+	}
+	if allTags := mergeTags(existingTags, tags); len(allTags) > 0 {
 		fullContent.WriteString("tags:\n")
-		for _, tag := range tags {
+		for _, tag := range allTags {
 			fullContent.WriteString(fmt.Sprintf("  - %s\n", tag))
 		}
 	}
 	fullContent.WriteString("---\n\n")
-	fullContent.WriteString(content)
+	fullContent.WriteString(body)
 
-	if err := os.WriteFile(filePath, []byte(fullContent.String()), 0644); err != nil {
+	// O_EXCL so a note appearing since freeNotePath looked is not clobbered
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("%w: %s", errNoteExists, relPath)
+	} else if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(fullContent.String()); err != nil {
+		file.Close()
 		return "", err
 	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	v.invalidateNote(relPath)
 
-	relPath, _ := filepath.Rel(v.Path, filePath)
+	v.audit("create", relPath, fmt.Sprintf("+%d lines", lineCount(fullContent.String())))
 	return relPath, nil
 }
 
+// freeNotePath returns filePath if nothing is there yet. Otherwise it is
+// errNoteExists, or with OnConflictSuffix the first free "-N" variant.
+func freeNotePath(filePath, onConflict string) (string, error) {
+	if _, err := os.Stat(filePath); errors.Is(err, fs.ErrNotExist) {
+		return filePath, nil
+	}
+	if onConflict != OnConflictSuffix {
+		return filePath, errNoteExists
+	}
+
+	base := strings.TrimSuffix(filePath, ".md")
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d.md", base, i)
+		if _, err := os.Stat(candidate); errors.Is(err, fs.ErrNotExist) {
+			return candidate, nil
+		}
+	}
+}
+
 // UpdateNote updates an existing note
 func (v *ObsidianVault) UpdateNote(notePath, content string, append bool) error {
 	fullPath := filepath.Join(v.Path, notePath)
 
+	var summary string
 	if append {
 		existing, err := os.ReadFile(fullPath)
 		if err != nil {
 			return fmt.Errorf("note not found: %s", notePath)
 		}
+		summary = fmt.Sprintf("+%d lines appended", lineCount(content))
 		content = string(existing) + "\n\n" + content
+	} else {
+		existing, _ := os.ReadFile(fullPath)
+		summary = fmt.Sprintf("%d -> %d lines", lineCount(string(existing)), lineCount(content))
+	}
+
+	if v.ReadOnly {
+		return nil
+	}
+
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	v.invalidateNote(notePath)
+
+	v.audit("update", notePath, summary)
+	return nil
+}
+
+// DiffNote returns a unified diff from the note's current content to
+// newContent, empty when they are equal. A missing note diffs from empty.
+func (v *ObsidianVault) DiffNote(notePath, newContent string) (string, error) {
+	existing, err := os.ReadFile(filepath.Join(v.Path, notePath))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(newContent),
+		FromFile: "a/" + notePath,
+		ToFile:   "b/" + notePath,
+		Context:  3,
+	})
+}
+
+// updatedContent returns what UpdateNote would write for content
+func (v *ObsidianVault) updatedContent(notePath, content string, append bool) (string, error) {
+	if !append {
+		return content, nil
+	}
+	existing, err := os.ReadFile(filepath.Join(v.Path, notePath))
+	if err != nil {
+		return "", fmt.Errorf("note not found: %s", notePath)
+	}
+	return string(existing) + "\n\n" + content, nil
+}
+
+// LinkNotes adds a [[toNote]] wikilink to fromPath under a "## <section>"
+// heading (default "Related"), creating the section at the end of the note
+// if needed. Nothing changes when the note already links to toNote.
+func (v *ObsidianVault) LinkNotes(fromPath, toPath, section string) error {
+	if section == "" {
+		section = "Related"
+	}
+
+	fullPath := filepath.Join(v.Path, fromPath)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("note not found: %s", fromPath)
+	}
+	if _, err := os.Stat(filepath.Join(v.Path, toPath)); err != nil {
+		return fmt.Errorf("note not found: %s", toPath)
+	}
+
+	// Obsidian resolves wikilinks by note name
+	target := strings.TrimSuffix(filepath.Base(toPath), ".md")
+	text := string(content)
+	if strings.Contains(text, "[["+target+"]]") || strings.Contains(text, "[["+target+"|") {
+		return nil
+	}
+
+	link := "- [[" + target + "]]"
+	heading := "## " + section
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	start := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == heading {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		lines = append(lines, "", heading, link)
+	} else {
+		// Insert after the section's last non-blank line
+		insert := start + 1
+		for i := start + 1; i < len(lines); i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(trimmed, "# ") || strings.HasPrefix(trimmed, "## ") {
+				break
+			}
+			if trimmed != "" {
+				insert = i + 1
+			}
+		}
+		lines = append(lines[:insert], append([]string{link}, lines[insert:]...)...)
+	}
+
+	if v.ReadOnly {
+		return nil
+	}
+
+	if err := os.WriteFile(fullPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
 	}
+	v.invalidateNote(fromPath)
 
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	v.audit("link", fromPath, "+[["+target+"]]")
+	return nil
 }
 
-// ListNotes lists all notes in the vault or a folder
-func (v *ObsidianVault) ListNotes(folder string) ([]NoteInfo, error) {
+// ListNotes lists the notes in the vault or a folder, including subfolders
+// when recursive is set
+func (v *ObsidianVault) ListNotes(folder string, recursive bool) ([]NoteInfo, error) {
 	searchPath := v.Path
 	if folder != "" {
 		searchPath = filepath.Join(v.Path, folder)
@@ -193,6 +616,11 @@ func (v *ObsidianVault) ListNotes(folder string) ([]NoteInfo, error) {
 			return nil
 		}
 
+		// Without recursion only the folder's own notes are listed
+		if !recursive && info.IsDir() && path != searchPath {
+			return filepath.SkipDir
+		}
+
 		if !info.IsDir() && strings.HasSuffix(path, ".md") {
 			relPath, _ := filepath.Rel(v.Path, path)
 			notes = append(notes, NoteInfo{
@@ -213,16 +641,108 @@ func (v *ObsidianVault) ListNotes(folder string) ([]NoteInfo, error) {
 	return notes, err
 }
 
-// GetBacklinks finds all notes that link to the specified note
-func (v *ObsidianVault) GetBacklinks(notePath string) ([]NoteInfo, error) {
+// attachmentExtensions are the non-markdown files ListAttachments returns
+var attachmentExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".bmp": true,
+	".pdf": true,
+	".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".flac": true,
+	".mp4": true, ".webm": true, ".mov": true, ".mkv": true,
+}
+
+// ListAttachments lists attachment files in the vault or a folder, newest first
+func (v *ObsidianVault) ListAttachments(folder string) ([]NoteInfo, error) {
+	searchPath := v.Path
+	if folder != "" {
+		searchPath = filepath.Join(v.Path, folder)
+	}
+
+	var attachments []NoteInfo
+
+	err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !info.IsDir() && attachmentExtensions[strings.ToLower(filepath.Ext(path))] {
+			relPath, _ := filepath.Rel(v.Path, path)
+			attachments = append(attachments, NoteInfo{
+				Path:     relPath,
+				Title:    info.Name(),
+				Size:     info.Size(),
+				Modified: info.ModTime(),
+			})
+		}
+		return nil
+	})
+
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].Modified.After(attachments[j].Modified)
+	})
+
+	return attachments, err
+}
+
+// RecentNotes returns notes modified within since (7 days when zero), newest
+// first, capped at limit when limit > 0
+func (v *ObsidianVault) RecentNotes(limit int, since time.Duration) ([]NoteInfo, error) {
+	if since <= 0 {
+		since = 7 * 24 * time.Hour
+	}
+	cutoff := time.Now().Add(-since)
+
+	notes, err := v.ListNotes("", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var recent []NoteInfo
+	for _, note := range notes {
+		// ListNotes is sorted newest first
+		if note.Modified.Before(cutoff) || (limit > 0 && len(recent) >= limit) {
+			break
+		}
+
+		if content, err := os.ReadFile(filepath.Join(v.Path, note.Path)); err == nil {
+			note.Preview = notePreview(string(content), 200)
+		}
+		recent = append(recent, note)
+	}
+
+	return recent, nil
+}
+
+// GetBacklinks finds all notes that link to the specified note or
+// attachment, by name or by one of its frontmatter aliases. With
+// embedsOnly, only ![[embeds]] and ![](images) count.
+func (v *ObsidianVault) GetBacklinks(notePath string, embedsOnly bool) ([]NoteInfo, error) {
 	noteName := strings.TrimSuffix(filepath.Base(notePath), ".md")
 	var backlinks []NoteInfo
 
+	names := []string{regexp.QuoteMeta(noteName)}
+	if strings.HasSuffix(notePath, ".md") {
+		if content, err := os.ReadFile(filepath.Join(v.Path, notePath)); err == nil {
+			for _, alias := range noteAliases(string(content)) {
+				names = append(names, regexp.QuoteMeta(alias))
+			}
+		}
+	}
+
+	// Obsidian resolves links case-insensitively. The name must be followed
+	// by a heading/block anchor, alias or the closing brackets, so a link
+	// to "Note Long" is not a backlink of "Note".
+	wikilink := fmt.Sprintf(`(?i)\[\[(?:[^\]|#]*/)?(?:%s)(?:\.md)?(?:#[^\]|]*)?(?:\|[^\]]*)?\]\]`, strings.Join(names, "|"))
+	mdlink := fmt.Sprintf(`(?i)\[.*?\]\(%s(?:#[^)]*)?\)`, regexp.QuoteMeta(notePath))
+
 	// Compile patterns
 	patterns := []*regexp.Regexp{
-		regexp.MustCompile(fmt.Sprintf(`\[\[%s\]\]`, regexp.QuoteMeta(noteName))),
-		regexp.MustCompile(fmt.Sprintf(`\[\[%s\|.*?\]\]`, regexp.QuoteMeta(noteName))),
-		regexp.MustCompile(fmt.Sprintf(`\[.*?\]\(%s\)`, regexp.QuoteMeta(notePath))),
+		regexp.MustCompile(`!` + wikilink),
+		regexp.MustCompile(`!` + mdlink),
+	}
+	if !embedsOnly {
+		patterns = append(patterns,
+			regexp.MustCompile(wikilink),
+			regexp.MustCompile(mdlink),
+		)
 	}
 
 	err := filepath.Walk(v.Path, func(path string, info os.FileInfo, err error) error {
@@ -273,57 +793,350 @@ func (v *ObsidianVault) GetBacklinks(notePath string) ([]NoteInfo, error) {
 	return backlinks, err
 }
 
-// GetTags returns all tags used in the vault
+var hashtagPattern = regexp.MustCompile(`#([\w/\-]+)`)
+
+// GetTags returns all tags used in the vault with their number of uses.
+// Tags come from the note index, so repeated calls only read the notes that
+// changed.
 func (v *ObsidianVault) GetTags() (map[string]int, error) {
-	tags := make(map[string]int)
-	hashtagPattern := regexp.MustCompile(`#([\w/\-]+)`)
+	index, err := v.scanIndex()
 
-	err := filepath.Walk(v.Path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+	tags := make(map[string]int)
+	for _, entry := range index {
+		for tag, count := range entry.tags {
+			tags[tag] += count
 		}
+	}
+	return tags, err
+}
 
-		if !info.IsDir() && strings.HasSuffix(path, ".md") {
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return nil
-			}
+// HeadingInfo is one heading of a note outline
+type HeadingInfo struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Line   int    `json:"line"`   // 1-based
+	Offset int    `json:"offset"` // byte offset of the heading line
+}
 
-			// Find hashtags
-			matches := hashtagPattern.FindAllStringSubmatch(string(content), -1)
-			for _, match := range matches {
-				if len(match) > 1 {
-					tags[match[1]]++
-				}
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+
+// Outline returns the markdown headings of a note in order, skipping
+// frontmatter and fenced code blocks
+func (v *ObsidianVault) Outline(notePath string) ([]HeadingInfo, error) {
+	note, err := v.ReadNote(notePath)
+	if err != nil {
+		return nil, err
+	}
+
+	headings := []HeadingInfo{}
+	inFrontmatter := strings.HasPrefix(note.Content, "---\n")
+	fence := ""
+	offset := 0
+
+	for i, line := range strings.Split(note.Content, "\n") {
+		lineOffset := offset
+		offset += len(line) + 1
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case inFrontmatter:
+			if i > 0 && trimmed == "---" {
+				inFrontmatter = false
 			}
+			continue
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```"):
+			fence = "```"
+			continue
+		case strings.HasPrefix(trimmed, "~~~"):
+			fence = "~~~"
+			continue
 		}
-		return nil
-	})
 
-	return tags, err
+		if match := headingPattern.FindStringSubmatch(line); match != nil {
+			headings = append(headings, HeadingInfo{
+				Level:  len(match[1]),
+				Text:   match[2],
+				Line:   i + 1,
+				Offset: lineOffset,
+			})
+		}
+	}
+
+	return headings, nil
 }
 
-// Helper functions
+// ReadSection returns the content under heading, up to the next heading of
+// the same or a higher level. The heading is matched case-insensitively.
+func (v *ObsidianVault) ReadSection(notePath, heading string) (string, error) {
+	note, err := v.ReadNote(notePath)
+	if err != nil {
+		return "", err
+	}
+	headings, err := v.Outline(notePath)
+	if err != nil {
+		return "", err
+	}
 
-func getRegexpFlags(flags int) string {
-	if flags == regexp.FlagCaseInsensitive {
-		return "i"
+	heading = strings.TrimSpace(strings.TrimLeft(heading, "# "))
+	for i, h := range headings {
+		if !strings.EqualFold(h.Text, heading) {
+			continue
+		}
+
+		start := len(note.Content)
+		if nl := strings.Index(note.Content[h.Offset:], "\n"); nl >= 0 {
+			start = h.Offset + nl + 1
+		}
+		end := len(note.Content)
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Offset
+				break
+			}
+		}
+		return strings.TrimSpace(note.Content[start:end]), nil
 	}
-	return ""
-}
 
-func sanitizeFilename(name string) string {
-	// Remove invalid characters
-	re := regexp.MustCompile(`[<>:"/\\|?*]`)
-	return re.ReplaceAllString(name, "")
+	available := make([]string, len(headings))
+	for i, h := range headings {
+		available[i] = strings.Repeat("#", h.Level) + " " + h.Text
+	}
+	if len(available) == 0 {
+		return "", fmt.Errorf("heading %q not found, %s has no headings", heading, notePath)
+	}
+	return "", fmt.Errorf("heading %q not found, available: %s", heading, strings.Join(available, ", "))
 }
 
-// RegisterObsidianTools registers Obsidian tools with the tool registry
-func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
-	// Search notes
+// tableDelimiterPattern matches a GFM table delimiter row like | --- | :-: |
+var tableDelimiterPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// ExtractTables parses the GitHub-flavored markdown tables in a note, in
+// document order, into rows of header -> cell maps. Rows with missing cells
+// are padded with empty strings and extra cells are dropped.
+func (v *ObsidianVault) ExtractTables(notePath string) ([][]map[string]string, error) {
+	note, err := v.ReadNote(notePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := [][]map[string]string{}
+	lines := strings.Split(note.Content, "\n")
+	inCodeBlock := false
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || !strings.Contains(line, "|") || i+1 >= len(lines) {
+			continue
+		}
+		if !tableDelimiterPattern.MatchString(strings.TrimSpace(lines[i+1])) {
+			continue
+		}
+
+		headers := splitTableRow(line)
+		for j, header := range headers {
+			if header == "" {
+				headers[j] = fmt.Sprintf("column%d", j+1)
+			}
+		}
+
+		rows := []map[string]string{}
+		for i += 2; i < len(lines); i++ {
+			rowLine := strings.TrimSpace(lines[i])
+			if rowLine == "" || !strings.Contains(rowLine, "|") {
+				break
+			}
+
+			cells := splitTableRow(rowLine)
+			row := make(map[string]string, len(headers))
+			for j, header := range headers {
+				if j < len(cells) {
+					row[header] = cells[j]
+				} else {
+					row[header] = ""
+				}
+			}
+			rows = append(rows, row)
+		}
+		i-- // Re-check the line that ended the table
+
+		tables = append(tables, rows)
+	}
+
+	return tables, nil
+}
+
+// Helper functions
+
+// splitTableRow splits a markdown table row on unescaped pipes, dropping the
+// optional leading and trailing pipe and unescaping \| inside cells
+func splitTableRow(line string) []string {
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func getRegexpFlags(flags int) string {
+	if flags == regexp.FlagCaseInsensitive {
+		return "i"
+	}
+	return ""
+}
+
+// notePreview returns the start of a note's body, without frontmatter, up to maxLen bytes
+func notePreview(content string, maxLen int) string {
+	_, body, _ := splitFrontmatter(content)
+
+	preview := strings.TrimSpace(body)
+	if len(preview) > maxLen {
+		preview = truncateUTF8(preview, maxLen) + "..."
+	}
+	return preview
+}
+
+// splitFrontmatter separates a leading --- frontmatter block from the body.
+// It reports false, returning content as the body, when there is none.
+func splitFrontmatter(content string) (string, string, bool) {
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content, false
+	}
+	end := strings.Index(content[3:], "\n---")
+	if end < 0 {
+		return "", content, false
+	}
+
+	frontmatter := strings.Trim(content[3:3+end], "\n")
+	body := content[3+end+4:]
+	if nl := strings.Index(body, "\n"); nl >= 0 && strings.TrimSpace(body[:nl]) == "" {
+		body = body[nl+1:]
+	}
+	return frontmatter, strings.TrimLeft(body, "\n"), true
+}
+
+// parseFrontmatter splits frontmatter into its lines minus the tags key,
+// the set of top-level keys and the tags it lists in block or inline form
+func parseFrontmatter(frontmatter string) ([]string, map[string]bool, []string) {
+	var lines, tags []string
+	keys := map[string]bool{}
+	if frontmatter == "" {
+		return lines, keys, tags
+	}
+
+	inTags := false
+	for _, line := range strings.Split(frontmatter, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inTags && strings.HasPrefix(trimmed, "- ") {
+			tags = append(tags, strings.Trim(strings.TrimSpace(trimmed[2:]), `"'`))
+			continue
+		}
+		inTags = false
+
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			lines = append(lines, line)
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "tags" {
+			value = strings.Trim(strings.TrimSpace(value), "[]")
+			if value == "" {
+				inTags = true
+			}
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.Trim(strings.TrimSpace(tag), `"'`); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			continue
+		}
+
+		keys[key] = true
+		lines = append(lines, line)
+	}
+
+	return lines, keys, tags
+}
+
+// mergeTags appends added to existing, skipping duplicates
+func mergeTags(existing, added []string) []string {
+	seen := make(map[string]bool, len(existing)+len(added))
+	var merged []string
+	for _, tag := range append(append([]string{}, existing...), added...) {
+		if !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// expandPath expands environment variables and a leading ~ or ~/ to the
+// home directory. The ~user form is not supported.
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, path[1:]), nil
+	}
+	if strings.HasPrefix(path, "~") {
+		return "", fmt.Errorf("unsupported vault path %q: ~user is not expanded, use an absolute path", path)
+	}
+
+	return path, nil
+}
+
+func sanitizeFilename(name string) string {
+	// Remove invalid characters
+	re := regexp.MustCompile(`[<>:"/\\|?*]`)
+	return re.ReplaceAllString(name, "")
+}
+
+// requiredArg returns the string argument name of a tool call, or an error
+// when the model left it out or sent something other than a string
+func requiredArg(args map[string]interface{}, name string) (string, error) {
+	value, _ := args[name].(string)
+	if value == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	return value, nil
+}
+
+// RegisterObsidianTools registers Obsidian tools with the tool registry
+func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
+	// Search notes
 	registry.Register(Tool{
 		Name:        "search_obsidian_notes",
-		Description: "Search for notes in the Obsidian vault containing specific text",
+		Description: "Search for notes in the Obsidian vault containing specific text, in their content or frontmatter aliases",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -336,16 +1149,43 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 					"description": "Whether the search should be case sensitive",
 					"default":     false,
 				},
+				"folder": map[string]interface{}{
+					"type":        "string",
+					"description": "Only search notes under this subfolder (optional)",
+					"default":     "",
+				},
+				"highlight": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Wrap matches in the preview in ==markers== instead of returning their character offsets",
+					"default":     false,
+				},
+				"plain": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Strip frontmatter and markdown syntax from the preview; false returns the raw lines",
+					"default":     true,
+				},
 			},
 			"required": []string{"query"},
 		},
 		Function: func(args map[string]interface{}) (interface{}, error) {
-			query := args["query"].(string)
+			query, err := requiredArg(args, "query")
+			if err != nil {
+				return nil, err
+			}
 			caseSensitive := false
 			if cs, ok := args["case_sensitive"].(bool); ok {
 				caseSensitive = cs
 			}
-			return vault.SearchNotes(query, caseSensitive)
+			folder := ""
+			if f, ok := args["folder"].(string); ok {
+				folder = f
+			}
+			highlight, _ := args["highlight"].(bool)
+			plain := true
+			if p, ok := args["plain"].(bool); ok {
+				plain = p
+			}
+			return vault.SearchNotes(query, caseSensitive, folder, highlight, plain)
 		},
 	})
 
@@ -358,21 +1198,61 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 			"properties": map[string]interface{}{
 				"note_path": map[string]interface{}{
 					"type":        "string",
-					"description": "Path to the note relative to vault root",
+					"description": "Path to the note relative to vault root, or one of its aliases",
 				},
 			},
 			"required": []string{"note_path"},
 		},
 		Function: func(args map[string]interface{}) (interface{}, error) {
-			notePath := args["note_path"].(string)
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
 			return vault.ReadNote(notePath)
 		},
 	})
 
+	// Read lines
+	registry.Register(Tool{
+		Name:        "read_lines",
+		Description: "Read a range of lines of a note, e.g. around a line number from search_obsidian_notes matches",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"note_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the note relative to vault root, or one of its aliases",
+				},
+				"start": map[string]interface{}{
+					"type":        "integer",
+					"description": "First line to read, numbered from 1",
+				},
+				"end": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("Last line to read, inclusive (optional, default start+%d, at most %d lines)", DefaultReadLines-1, MaxReadLines),
+				},
+			},
+			"required": []string{"note_path", "start"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
+			start, _ := args["start"].(float64)
+			end := 0
+			if e, ok := args["end"].(float64); ok {
+				end = int(e)
+			}
+			return vault.ReadLines(notePath, int(start), end)
+		},
+	})
+
 	// Create note
 	registry.Register(Tool{
 		Name:        "create_obsidian_note",
 		Description: "Create a new note in the Obsidian vault",
+		Destructive: true,
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -397,12 +1277,21 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 					},
 					"default": []string{},
 				},
+				"on_conflict": map[string]interface{}{
+					"type":        "string",
+					"description": "What to do if a note with this title exists: \"error\" (default) or \"suffix\" to create Title-1, Title-2, ...",
+					"enum":        []string{OnConflictError, OnConflictSuffix},
+					"default":     OnConflictError,
+				},
 			},
 			"required": []string{"title", "content"},
 		},
 		Function: func(args map[string]interface{}) (interface{}, error) {
-			title := args["title"].(string)
-			content := args["content"].(string)
+			title, err := requiredArg(args, "title")
+			if err != nil {
+				return nil, err
+			}
+			content, _ := args["content"].(string)
 			folder := ""
 			if f, ok := args["folder"].(string); ok {
 				folder = f
@@ -415,7 +1304,223 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 					}
 				}
 			}
-			return vault.CreateNote(title, content, folder, tags)
+			onConflict := OnConflictError
+			if c, ok := args["on_conflict"].(string); ok && c != "" {
+				onConflict = c
+			}
+			path, err := vault.CreateNote(title, content, folder, tags, onConflict)
+			if err != nil {
+				return nil, err
+			}
+			if vault.ReadOnly {
+				return fmt.Sprintf("(dry run) would create %s, nothing was written", path), nil
+			}
+			return map[string]string{"path": path, "on_conflict": onConflict}, nil
+		},
+	})
+
+	// Create note from template
+	registry.Register(Tool{
+		Name:        "create_note_from_template",
+		Description: "Create a new note from a template in the vault's templates folder, filling in {{title}}, {{date}}, {{time}} and custom {{variables}}",
+		Destructive: true,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{
+					"type":        "string",
+					"description": "Title of the new note",
+				},
+				"template": map[string]interface{}{
+					"type":        "string",
+					"description": "Template name, relative to the templates folder and without .md",
+				},
+				"folder": map[string]interface{}{
+					"type":        "string",
+					"description": "Subfolder within vault (optional)",
+					"default":     "",
+				},
+				"variables": map[string]interface{}{
+					"type":        "object",
+					"description": "Values for custom {{placeholders}} in the template (optional)",
+					"additionalProperties": map[string]interface{}{
+						"type": "string",
+					},
+				},
+			},
+			"required": []string{"title", "template"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			title, _ := args["title"].(string)
+			templateName, _ := args["template"].(string)
+			folder, _ := args["folder"].(string)
+			vars := map[string]string{}
+			if raw, ok := args["variables"].(map[string]interface{}); ok {
+				for key, value := range raw {
+					vars[key] = fmt.Sprint(value)
+				}
+			}
+			path, err := vault.CreateNoteFromTemplate(title, templateName, folder, vars)
+			if err == nil && vault.ReadOnly {
+				return fmt.Sprintf("(dry run) would create %s from %s, nothing was written", path, templateName), nil
+			}
+			return path, err
+		},
+	})
+
+	// Update note
+	updateArgs := func(args map[string]interface{}) (string, string, bool) {
+		notePath, _ := args["note_path"].(string)
+		content, _ := args["content"].(string)
+		appendMode, _ := args["append"].(bool)
+		return notePath, content, appendMode
+	}
+	updatePreview := func(args map[string]interface{}) (string, error) {
+		notePath, content, appendMode := updateArgs(args)
+		newContent, err := vault.updatedContent(notePath, content, appendMode)
+		if err != nil {
+			return "", err
+		}
+		diff, err := vault.DiffNote(notePath, newContent)
+		if err == nil && diff == "" {
+			diff = "(no changes)"
+		}
+		return diff, err
+	}
+	registry.Register(Tool{
+		Name:        "update_obsidian_note",
+		Description: "Replace or append to the content of an existing note. Set preview to get a unified diff without writing.",
+		Destructive: true,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"note_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the note relative to vault root",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "New content in Markdown format",
+				},
+				"append": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Append to the note instead of replacing it",
+					"default":     false,
+				},
+				"preview": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Return the diff of the change instead of writing it",
+					"default":     false,
+				},
+			},
+			"required": []string{"note_path", "content"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			if preview, _ := args["preview"].(bool); preview {
+				return updatePreview(args)
+			}
+			notePath, content, appendMode := updateArgs(args)
+			if err := vault.UpdateNote(notePath, content, appendMode); err != nil {
+				return nil, err
+			}
+			if vault.ReadOnly {
+				return fmt.Sprintf("(dry run) would update %s, nothing was written", notePath), nil
+			}
+			return fmt.Sprintf("Updated %s", notePath), nil
+		},
+		Preview: updatePreview,
+	})
+
+	// Link notes
+	registry.Register(Tool{
+		Name:        "link_obsidian_notes",
+		Description: "Add a [[wikilink]] from one note to another under a Related section, skipping existing links",
+		Destructive: true,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"from_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path of the note to add the link to",
+				},
+				"to_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path of the note to link to",
+				},
+				"section": map[string]interface{}{
+					"type":        "string",
+					"description": "Heading to put the link under (optional)",
+					"default":     "Related",
+				},
+			},
+			"required": []string{"from_path", "to_path"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			fromPath, err := requiredArg(args, "from_path")
+			if err != nil {
+				return nil, err
+			}
+			toPath, err := requiredArg(args, "to_path")
+			if err != nil {
+				return nil, err
+			}
+			section, _ := args["section"].(string)
+			if err := vault.LinkNotes(fromPath, toPath, section); err != nil {
+				return nil, err
+			}
+			if vault.ReadOnly {
+				return fmt.Sprintf("(dry run) would link %s to %s, nothing was written", fromPath, toPath), nil
+			}
+			return fmt.Sprintf("Linked %s to %s", fromPath, toPath), nil
+		},
+	})
+
+	// Move note
+	registry.Register(Tool{
+		Name:        "move_obsidian_note",
+		Description: "Move or rename a note, creating destination folders. Fails if a note already exists at the destination.",
+		Destructive: true,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Path of the note to move, relative to vault root",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "New path relative to vault root; a folder ending in / keeps the file name",
+				},
+				"update_links": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Rewrite links to the note in other notes so they keep working",
+					"default":     true,
+				},
+			},
+			"required": []string{"from", "to"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			from, err := requiredArg(args, "from")
+			if err != nil {
+				return nil, err
+			}
+			to, err := requiredArg(args, "to")
+			if err != nil {
+				return nil, err
+			}
+			updateLinks := true
+			if u, ok := args["update_links"].(bool); ok {
+				updateLinks = u
+			}
+			result, err := vault.MoveNote(from, to, updateLinks)
+			if err != nil {
+				return nil, err
+			}
+			if vault.ReadOnly {
+				return fmt.Sprintf("(dry run) would move %s to %s and update links in %d notes, nothing was written",
+					result.From, result.To, len(result.UpdatedNotes)), nil
+			}
+			return result, nil
 		},
 	})
 
@@ -431,6 +1536,11 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 					"description": "Subfolder to list (optional)",
 					"default":     "",
 				},
+				"recursive": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include notes in subfolders; false lists only the folder's own notes",
+					"default":     true,
+				},
 			},
 		},
 		Function: func(args map[string]interface{}) (interface{}, error) {
@@ -438,27 +1548,284 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 			if f, ok := args["folder"].(string); ok {
 				folder = f
 			}
-			return vault.ListNotes(folder)
+			recursive := true
+			if r, ok := args["recursive"].(bool); ok {
+				recursive = r
+			}
+			return vault.ListNotes(folder, recursive)
+		},
+	})
+
+	// Fuzzy title search
+	registry.Register(Tool{
+		Name:        "fuzzy_find_notes",
+		Description: "Find notes by approximate title, for when the exact name is unknown. Returns matches with a 0-1 score, best first",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Approximate note title",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of matches to return (optional)",
+					"default":     DefaultFuzzyLimit,
+				},
+			},
+			"required": []string{"query"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			query, err := requiredArg(args, "query")
+			if err != nil {
+				return nil, err
+			}
+			limit := DefaultFuzzyLimit
+			if l, ok := args["limit"].(float64); ok {
+				limit = int(l)
+			}
+			return vault.FuzzyFindNotes(query, limit)
+		},
+	})
+
+	// Semantic search
+	registry.Register(Tool{
+		Name:        "semantic_search_notes",
+		Description: "Find notes by meaning rather than exact words, using embeddings. Returns the closest notes with a cosine similarity score, best first",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "What the notes should be about",
+				},
+				"top_k": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of notes to return (optional)",
+					"default":     DefaultSemanticTopK,
+				},
+			},
+			"required": []string{"query"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			query, err := requiredArg(args, "query")
+			if err != nil {
+				return nil, err
+			}
+			topK := DefaultSemanticTopK
+			if k, ok := args["top_k"].(float64); ok {
+				topK = int(k)
+			}
+			return vault.SemanticSearch(query, topK)
+		},
+	})
+
+	// Recent notes
+	registry.Register(Tool{
+		Name:        "recent_obsidian_notes",
+		Description: "List recently modified notes, newest first, with a short preview",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of notes to return (optional)",
+					"default":     20,
+				},
+				"days": map[string]interface{}{
+					"type":        "number",
+					"description": "Only include notes modified within this many days",
+					"default":     7,
+				},
+			},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			limit := 20
+			if l, ok := args["limit"].(float64); ok {
+				limit = int(l)
+			}
+			days := 7.0
+			if d, ok := args["days"].(float64); ok {
+				days = d
+			}
+			return vault.RecentNotes(limit, time.Duration(days*float64(24*time.Hour)))
 		},
 	})
 
 	// Get backlinks
 	registry.Register(Tool{
 		Name:        "get_obsidian_backlinks",
-		Description: "Find all notes that link to a specific note",
+		Description: "Find all notes that link to a specific note, or that embed an attachment such as an image",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"note_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the note or attachment to find backlinks for",
+				},
+				"embeds_only": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Only count embeds like ![[image.png]]",
+					"default":     false,
+				},
+			},
+			"required": []string{"note_path"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
+			embedsOnly, _ := args["embeds_only"].(bool)
+			return vault.GetBacklinks(notePath, embedsOnly)
+		},
+	})
+
+	// Outgoing links
+	registry.Register(Tool{
+		Name:        "outgoing_links",
+		Description: "List the links and embeds in a note with their type, target, resolved vault path (empty if missing) and whether they are external URLs",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"note_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the note, relative to the vault",
+				},
+			},
+			"required": []string{"note_path"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
+			return vault.OutgoingLinks(notePath)
+		},
+	})
+
+	// List attachments
+	registry.Register(Tool{
+		Name:        "list_obsidian_attachments",
+		Description: "List non-markdown attachments (images, PDFs, audio, video) in the vault or a folder",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"folder": map[string]interface{}{
+					"type":        "string",
+					"description": "Subfolder to list (optional)",
+					"default":     "",
+				},
+			},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			folder := ""
+			if f, ok := args["folder"].(string); ok {
+				folder = f
+			}
+			return vault.ListAttachments(folder)
+		},
+	})
+
+	// Read attachment
+	registry.Register(Tool{
+		Name:        "read_obsidian_attachment",
+		Description: "Read an image attachment from the vault as base64 with its MIME type, e.g. to describe a diagram",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the attachment relative to vault root, as returned by list_obsidian_attachments",
+				},
+			},
+			"required": []string{"path"},
+		},
+		// The attachment size cap applies instead, a cut base64 string is useless
+		MaxResultBytes: -1,
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			path, err := requiredArg(args, "path")
+			if err != nil {
+				return nil, err
+			}
+			return vault.ReadAttachmentBase64(path)
+		},
+	})
+
+	// Note outline
+	registry.Register(Tool{
+		Name:        "note_outline",
+		Description: "Get the heading structure of a note (level, text, line and character offset) without reading its body",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"note_path": map[string]interface{}{
 					"type":        "string",
-					"description": "Path to the note to find backlinks for",
+					"description": "Path to the note relative to vault root",
 				},
 			},
 			"required": []string{"note_path"},
 		},
 		Function: func(args map[string]interface{}) (interface{}, error) {
-			notePath := args["note_path"].(string)
-			return vault.GetBacklinks(notePath)
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
+			return vault.Outline(notePath)
+		},
+	})
+
+	// Read note section
+	registry.Register(Tool{
+		Name:        "read_note_section",
+		Description: "Read only the content under one heading of a note, up to the next heading of the same or higher level",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"note_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the note relative to vault root",
+				},
+				"heading": map[string]interface{}{
+					"type":        "string",
+					"description": "Heading text, as returned by note_outline",
+				},
+			},
+			"required": []string{"note_path", "heading"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
+			heading, err := requiredArg(args, "heading")
+			if err != nil {
+				return nil, err
+			}
+			return vault.ReadSection(notePath, heading)
+		},
+	})
+
+	// Extract tables
+	registry.Register(Tool{
+		Name:        "extract_tables",
+		Description: "Parse the markdown tables in a note into rows of column -> value objects, in document order",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"note_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the note relative to vault root",
+				},
+			},
+			"required": []string{"note_path"},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			notePath, err := requiredArg(args, "note_path")
+			if err != nil {
+				return nil, err
+			}
+			return vault.ExtractTables(notePath)
 		},
 	})
 
@@ -474,4 +1841,17 @@ func RegisterObsidianTools(registry *ToolRegistry, vault *ObsidianVault) {
 			return vault.GetTags()
 		},
 	})
+
+	// Vault stats
+	registry.Register(Tool{
+		Name:        "vault_stats",
+		Description: "Get an overview of the vault: note, tag and word counts, the biggest note, orphan notes and dead links",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+		Function: func(args map[string]interface{}) (interface{}, error) {
+			return vault.Summary()
+		},
+	})
 }
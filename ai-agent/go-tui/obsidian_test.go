@@ -0,0 +1,399 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testVault creates a vault in a temporary directory holding files, keyed
+// by vault-relative path
+func testVault(t *testing.T, files map[string]string) *ObsidianVault {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vault, err := NewObsidianVault(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vault
+}
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("VAULTS", "/srv/vaults")
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"~", home},
+		{"~/vault", filepath.Join(home, "vault")},
+		{"$HOME/vault", filepath.Join(home, "vault")},
+		{"${VAULTS}/notes", "/srv/vaults/notes"},
+		{"/abs/vault", "/abs/vault"},
+	}
+	for _, tc := range cases {
+		got, err := expandPath(tc.path)
+		if err != nil {
+			t.Errorf("expandPath(%q): %v", tc.path, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("expandPath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	if _, err := expandPath("~alice/vault"); err == nil {
+		t.Error("expandPath(~alice/vault) succeeded, want an unsupported ~user error")
+	}
+}
+
+func TestNewObsidianVaultExpandsHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.Mkdir(filepath.Join(home, "vault"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"~/vault", "$HOME/vault"} {
+		vault, err := NewObsidianVault(path)
+		if err != nil {
+			t.Errorf("NewObsidianVault(%q): %v", path, err)
+			continue
+		}
+		if want := filepath.Join(home, "vault"); vault.Path != want {
+			t.Errorf("NewObsidianVault(%q).Path = %q, want %q", path, vault.Path, want)
+		}
+	}
+
+	vault, err := NewObsidianVault("~")
+	if err != nil || vault.Path != home {
+		t.Errorf("NewObsidianVault(~) = %v, %v, want the home directory", vault, err)
+	}
+}
+
+func readVaultFile(t *testing.T, vault *ObsidianVault, relPath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(vault.Path, relPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCreateNoteMergesExistingFrontmatter(t *testing.T) {
+	vault := testVault(t, nil)
+
+	content := "---\ntitle: Weekly review\ntags: [review]\n---\n# Week 41\n\nShipped it.\n"
+	path, err := vault.CreateNote("Week 41", content, "", []string{"work", "review"}, OnConflictError)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written := readVaultFile(t, vault, path)
+	frontmatter, body, ok := splitFrontmatter(written)
+	if !ok {
+		t.Fatalf("no frontmatter in\n%s", written)
+	}
+	if strings.Contains(body, "---") {
+		t.Errorf("a second frontmatter block was written:\n%s", written)
+	}
+	if strings.TrimSpace(body) != "# Week 41\n\nShipped it." {
+		t.Errorf("body = %q", body)
+	}
+
+	lines, keys, tags := parseFrontmatter(frontmatter)
+	if !keys["title"] || !keys["created"] {
+		t.Errorf("keys = %v, want title kept and created added", keys)
+	}
+	if !reflect.DeepEqual(tags, []string{"review", "work"}) {
+		t.Errorf("tags = %v, want [review work] without duplicates", tags)
+	}
+	if !slices.Contains(lines, "title: Weekly review") {
+		t.Errorf("lines = %v, want the title line kept", lines)
+	}
+}
+
+func TestCreateNoteKeepsExistingCreated(t *testing.T) {
+	vault := testVault(t, nil)
+
+	content := "---\ncreated: 2020-01-02T03:04:05Z\n---\nOld note\n"
+	path, err := vault.CreateNote("Imported", content, "", nil, OnConflictError)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written := readVaultFile(t, vault, path)
+	if n := strings.Count(written, "created:"); n != 1 {
+		t.Errorf("created appears %d times in\n%s", n, written)
+	}
+	if !strings.Contains(written, "created: 2020-01-02T03:04:05Z") {
+		t.Errorf("the existing created date was replaced:\n%s", written)
+	}
+}
+
+func TestCreateNoteWithoutFrontmatter(t *testing.T) {
+	vault := testVault(t, nil)
+
+	path, err := vault.CreateNote("Plain", "Just text\n", "Inbox", []string{"idea"}, OnConflictError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join("Inbox", "Plain.md") {
+		t.Errorf("path = %q", path)
+	}
+
+	written := readVaultFile(t, vault, path)
+	if !strings.HasPrefix(written, "---\ncreated: ") || !strings.Contains(written, "tags:\n  - idea\n---\n\nJust text\n") {
+		t.Errorf("unexpected note:\n%s", written)
+	}
+}
+
+func TestUpdateNoteDryRunAppend(t *testing.T) {
+	vault := testVault(t, map[string]string{"Log.md": "day one\n"})
+	vault.ReadOnly = true
+
+	if err := vault.UpdateNote("Missing.md", "entry", true); err == nil {
+		t.Error("dry-run append to a missing note succeeded, want note not found")
+	}
+	if err := vault.UpdateNote("Log.md", "day two", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readVaultFile(t, vault, "Log.md"); got != "day one\n" {
+		t.Errorf("dry run wrote the note: %q", got)
+	}
+}
+
+// backlinkPaths returns the sorted paths of the notes linking to notePath
+func backlinkPaths(t *testing.T, vault *ObsidianVault, notePath string, embedsOnly bool) []string {
+	t.Helper()
+	backlinks, err := vault.GetBacklinks(notePath, embedsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, note := range backlinks {
+		paths = append(paths, note.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestGetBacklinksLinkVariants(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Note.md":          "# Note\n\nSee [[Note#Section]] for a self link.\n",
+		"heading.md":       "Read [[Note#Section]] first.\n",
+		"block.md":         "Quoted in [[Note#^abc123]].\n",
+		"case.md":          "Lowercase [[note]] still resolves.\n",
+		"aliased.md":       "Shown as [[Note|the note]].\n",
+		"folder.md":        "Linked as [[Projects/Note]].\n",
+		"embed.md":         "![[Note]]\n",
+		"markdown.md":      "A [plain link](Note.md#Section).\n",
+		"prefix.md":        "Only [[NoteLong]] and [[Note Long|other]] here.\n",
+		"Projects/Long.md": "Also [[Notes]] and [[ANote]].\n",
+	})
+
+	got := backlinkPaths(t, vault, "Note.md", false)
+	want := []string{"aliased.md", "block.md", "case.md", "embed.md", "folder.md", "heading.md", "markdown.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("backlinks = %v, want %v", got, want)
+	}
+
+	if got := backlinkPaths(t, vault, "Note.md", true); !reflect.DeepEqual(got, []string{"embed.md"}) {
+		t.Errorf("embeds only = %v, want [embed.md]", got)
+	}
+}
+
+func TestGetBacklinksPrefixCollision(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Note.md":      "short",
+		"Note Long.md": "long",
+		"a.md":         "[[Note Long]]\n",
+		"b.md":         "[[Note]]\n",
+	})
+
+	if got := backlinkPaths(t, vault, "Note.md", false); !reflect.DeepEqual(got, []string{"b.md"}) {
+		t.Errorf("backlinks of Note = %v, want [b.md]", got)
+	}
+	if got := backlinkPaths(t, vault, "Note Long.md", false); !reflect.DeepEqual(got, []string{"a.md"}) {
+		t.Errorf("backlinks of Note Long = %v, want [a.md]", got)
+	}
+}
+
+func notePaths(notes []NoteInfo) []string {
+	var paths []string
+	for _, note := range notes {
+		paths = append(paths, note.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestListNotesRecursive(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Root.md":                  "root",
+		"Projects/Plan.md":         "plan",
+		"Projects/Ideas.md":        "ideas",
+		"Projects/Alpha/Design.md": "design",
+		"Projects/Alpha/image.png": "not a note",
+		"Archive/Old.md":           "old",
+	})
+
+	cases := []struct {
+		folder    string
+		recursive bool
+		want      []string
+	}{
+		{"Projects", false, []string{"Projects/Ideas.md", "Projects/Plan.md"}},
+		{"Projects", true, []string{"Projects/Alpha/Design.md", "Projects/Ideas.md", "Projects/Plan.md"}},
+		{"", false, []string{"Root.md"}},
+		{"", true, []string{"Archive/Old.md", "Projects/Alpha/Design.md", "Projects/Ideas.md", "Projects/Plan.md", "Root.md"}},
+	}
+	for _, tc := range cases {
+		notes, err := vault.ListNotes(tc.folder, tc.recursive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := notePaths(notes); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ListNotes(%q, %v) = %v, want %v", tc.folder, tc.recursive, got, tc.want)
+		}
+	}
+}
+
+func TestListNotesToolRecursiveDefault(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Projects/Plan.md":         "plan",
+		"Projects/Alpha/Design.md": "design",
+	})
+	registry := NewToolRegistry()
+	RegisterObsidianTools(registry, vault)
+
+	for _, tc := range []struct {
+		args map[string]interface{}
+		want int
+	}{
+		{map[string]interface{}{"folder": "Projects"}, 2},
+		{map[string]interface{}{"folder": "Projects", "recursive": false}, 1},
+	} {
+		result, err := registry.ExecuteTool("list_obsidian_notes", tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if notes, _ := result.([]NoteInfo); len(notes) != tc.want {
+			t.Errorf("list_obsidian_notes %v = %v, want %d notes", tc.args, result, tc.want)
+		}
+	}
+}
+
+func TestSearchNotesFolder(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Projects/Alpha.md":        "Deadline is Friday.\n",
+		"Projects/Beta/Tasks.md":   "Another deadline here.\n",
+		"Personal/Birthday.md":     "deadline for the gift\n",
+		"Projects Archive/Done.md": "Old deadline.\n",
+	})
+
+	results, err := vault.SearchNotes("deadline", false, "Projects", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Projects/Alpha.md", "Projects/Beta/Tasks.md"}
+	if got := notePaths(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("search in Projects = %v, want %v", got, want)
+	}
+
+	results, err = vault.SearchNotes("deadline", true, "Projects", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notePaths(results); !reflect.DeepEqual(got, []string{"Projects/Beta/Tasks.md"}) {
+		t.Errorf("case-sensitive search in Projects = %v", got)
+	}
+
+	for _, folder := range []string{"../outside", "Projects/../../outside"} {
+		if _, err := vault.SearchNotes("deadline", false, folder, false, true); err == nil || !strings.Contains(err.Error(), "outside the vault") {
+			t.Errorf("SearchNotes in %q = %v, want an outside the vault error", folder, err)
+		}
+	}
+	if _, err := vault.SearchNotes("deadline", false, "Missing", false, true); err == nil {
+		t.Error("SearchNotes in a missing folder succeeded")
+	}
+}
+
+func TestCreateNoteConflict(t *testing.T) {
+	vault := testVault(t, nil)
+
+	first, err := vault.CreateNote("Meeting", "first version", "", nil, OnConflictError)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vault.CreateNote("Meeting", "second version", "", nil, OnConflictError); !errors.Is(err, errNoteExists) {
+		t.Errorf("second create = %v, want errNoteExists", err)
+	}
+	if _, err := vault.CreateNote("Meeting", "default version", "", nil, ""); !errors.Is(err, errNoteExists) {
+		t.Errorf("create without on_conflict = %v, want errNoteExists", err)
+	}
+	if written := readVaultFile(t, vault, first); !strings.Contains(written, "first version") {
+		t.Errorf("the first note was overwritten:\n%s", written)
+	}
+
+	var suffixed []string
+	for i := 0; i < 2; i++ {
+		path, err := vault.CreateNote("Meeting", fmt.Sprintf("copy %d", i), "", nil, OnConflictSuffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suffixed = append(suffixed, path)
+	}
+	if !reflect.DeepEqual(suffixed, []string{"Meeting-1.md", "Meeting-2.md"}) {
+		t.Errorf("suffixed paths = %v", suffixed)
+	}
+	for i, path := range suffixed {
+		if written := readVaultFile(t, vault, path); !strings.Contains(written, fmt.Sprintf("copy %d", i)) {
+			t.Errorf("%s = %q", path, written)
+		}
+	}
+	if written := readVaultFile(t, vault, first); !strings.Contains(written, "first version") {
+		t.Errorf("the first note was overwritten:\n%s", written)
+	}
+}
+
+func TestCreateNoteToolReportsPath(t *testing.T) {
+	vault := testVault(t, map[string]string{"Meeting.md": "existing"})
+	registry := NewToolRegistry()
+	RegisterObsidianTools(registry, vault)
+
+	if _, err := registry.ExecuteTool("create_obsidian_note", map[string]interface{}{"title": "Meeting", "content": "new"}); !errors.Is(err, errNoteExists) {
+		t.Errorf("create_obsidian_note on an existing title = %v, want errNoteExists", err)
+	}
+
+	result, err := registry.ExecuteTool("create_obsidian_note", map[string]interface{}{"title": "Meeting", "content": "new", "on_conflict": OnConflictSuffix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"path": "Meeting-1.md", "on_conflict": OnConflictSuffix}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %v, want %v", result, want)
+	}
+	if readVaultFile(t, vault, "Meeting.md") != "existing" {
+		t.Error("the existing note was overwritten")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Markdown syntax stripped by markdownToPlain. RE2 has no backreferences,
+// so every delimiter has its own pattern. A single _ only counts as
+// emphasis at word boundaries so snake_case survives.
+var (
+	headingPrefixPattern = regexp.MustCompile(`^\s*#{1,6}\s+`)
+	quotePrefixPattern   = regexp.MustCompile(`^\s*(>\s?)+`)
+	inlineCodePattern    = regexp.MustCompile("`([^`\n]+)`")
+	delimiterPatterns    = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`),
+		regexp.MustCompile(`__(\S(?:.*?\S)?)__`),
+		regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`),
+		regexp.MustCompile(`==(\S(?:.*?\S)?)==`),
+	}
+	starEmphasisPattern       = regexp.MustCompile(`(^|[^\w*])\*(\S(?:[^*]*?\S)?)\*`)
+	underscoreEmphasisPattern = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*?\S)?)_(\W|$)`)
+)
+
+// markdownToPlain turns one line of markdown into plain text for previews:
+// images and embeds are dropped, links keep only their text (a wikilink's
+// alias or target), and heading, quote, emphasis and code markers are
+// removed. It is deliberately lightweight; anything else passes through.
+func markdownToPlain(line string) string {
+	line = headingPrefixPattern.ReplaceAllString(line, "")
+	line = quotePrefixPattern.ReplaceAllString(line, "")
+
+	line = wikiLinkPattern.ReplaceAllStringFunc(line, func(match string) string {
+		m := wikiLinkPattern.FindStringSubmatch(match)
+		if m[1] == "!" {
+			return ""
+		}
+		target, alias, ok := strings.Cut(m[2], "|")
+		if ok {
+			return alias
+		}
+		target, _, _ = strings.Cut(target, "#")
+		return target
+	})
+	line = markdownLinkPattern.ReplaceAllStringFunc(line, func(match string) string {
+		m := markdownLinkPattern.FindStringSubmatch(match)
+		switch {
+		case m[1] == "!":
+			return ""
+		case m[2] == "":
+			return m[3]
+		}
+		return m[2]
+	})
+
+	line = inlineCodePattern.ReplaceAllString(line, "$1")
+	for _, pattern := range delimiterPatterns {
+		line = pattern.ReplaceAllString(line, "$1")
+	}
+	line = starEmphasisPattern.ReplaceAllString(line, "${1}${2}")
+	line = underscoreEmphasisPattern.ReplaceAllString(line, "${1}${2}${3}")
+
+	// Dropped images leave runs of spaces behind
+	return strings.Join(strings.Fields(line), " ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestMarkdownToPlain(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"## Weekly **review**", "Weekly review"},
+		{"> quoted > nested text", "quoted > nested text"},
+		{"> > deeply quoted", "deeply quoted"},
+		{"See [[Projects/Plan#Goals|the plan]] and [[Inbox]]", "See the plan and Inbox"},
+		{"Heading link [[Plan#Goals]]", "Heading link Plan"},
+		{"![[diagram.png]] Architecture ![alt](img/a.png) overview", "Architecture overview"},
+		{"Read [the docs](https://example.com) or <https://example.com>", "Read the docs or <https://example.com>"},
+		{"*italic*, _also italic_, __bold__, ~~gone~~ and ==marked==", "italic, also italic, bold, gone and marked"},
+		{"Run `go test ./...` with snake_case_name", "Run go test ./... with snake_case_name"},
+		{"2 * 3 * 4 stays", "2 * 3 * 4 stays"},
+		{"#tag and plain text", "#tag and plain text"},
+	}
+
+	for _, tc := range cases {
+		if got := markdownToPlain(tc.in); got != tc.want {
+			t.Errorf("markdownToPlain(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+const mixedMarkdownNote = `---
+tags: [search]
+summary: about search previews
+---
+# Search **previews**
+
+Plain search results read better with [[Design|the design]] and [a link](https://example.com) stripped.
+![[chart.png]]
+> A _quoted_ search tip
+`
+
+func TestSearchNotesPlainPreview(t *testing.T) {
+	vault := testVault(t, map[string]string{"Previews.md": mixedMarkdownNote})
+
+	results, err := vault.SearchNotes("search", false, "", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	want := "Search previews\nPlain search results read better with the design and a link stripped.\nA quoted search tip"
+	if results[0].Preview != want {
+		t.Errorf("plain preview =\n%s\nwant\n%s", results[0].Preview, want)
+	}
+	for _, m := range results[0].PreviewMatches {
+		if got := string([]rune(results[0].Preview)[m.Start:m.End]); got != "Search" && got != "search" {
+			t.Errorf("match %+v covers %q", m, got)
+		}
+	}
+}
+
+func TestSearchNotesRawPreview(t *testing.T) {
+	vault := testVault(t, map[string]string{"Previews.md": mixedMarkdownNote})
+
+	results, err := vault.SearchNotes("search", false, "", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	want := "tags: [search]\nsummary: about search previews\n# Search **previews**\nPlain search results read better with [[Design|the design]] and [a link](https://example.com) stripped.\n> A _quoted_ search tip"
+	if results[0].Preview != want {
+		t.Errorf("raw preview =\n%s\nwant\n%s", results[0].Preview, want)
+	}
+}
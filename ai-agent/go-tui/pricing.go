@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is what a model costs in USD per 1K tokens
+type ModelPrice struct {
+	Input  float64 `toml:"input"`
+	Output float64 `toml:"output"`
+}
+
+// Pricing maps provider type -> model -> price. A model matches its exact
+// name or the longest listed prefix (so "gpt-4o" covers "gpt-4o-2024-08-06"),
+// and "*" matches any model of the provider.
+type Pricing map[string]map[string]ModelPrice
+
+// DefaultPricing is the built-in price list, edit here when prices change.
+// Azure falls back to the OpenAI prices. [pricing.<provider>] tables in
+// config.toml override single entries.
+var DefaultPricing = Pricing{
+	"openai": {
+		"gpt-4o":        {Input: 0.0025, Output: 0.01},
+		"gpt-4o-mini":   {Input: 0.00015, Output: 0.0006},
+		"gpt-4-turbo":   {Input: 0.01, Output: 0.03},
+		"gpt-4":         {Input: 0.03, Output: 0.06},
+		"gpt-3.5-turbo": {Input: 0.0005, Output: 0.0015},
+		"o1":            {Input: 0.015, Output: 0.06},
+		"o1-mini":       {Input: 0.003, Output: 0.012},
+	},
+	"anthropic": {
+		"claude-3-5-sonnet": {Input: 0.003, Output: 0.015},
+		"claude-3-5-haiku":  {Input: 0.0008, Output: 0.004},
+		"claude-3-opus":     {Input: 0.015, Output: 0.075},
+		"claude-3-sonnet":   {Input: 0.003, Output: 0.015},
+		"claude-3-haiku":    {Input: 0.00025, Output: 0.00125},
+	},
+	"ollama": {
+		"*": {}, // local models are free
+	},
+}
+
+// mergePricing returns DefaultPricing with overrides applied on top
+func mergePricing(overrides Pricing) Pricing {
+	merged := make(Pricing, len(DefaultPricing))
+	for provider, models := range DefaultPricing {
+		merged[provider] = make(map[string]ModelPrice, len(models))
+		for model, price := range models {
+			merged[provider][model] = price
+		}
+	}
+	for provider, models := range overrides {
+		if merged[provider] == nil {
+			merged[provider] = make(map[string]ModelPrice, len(models))
+		}
+		for model, price := range models {
+			merged[provider][model] = price
+		}
+	}
+	return merged
+}
+
+// Price looks up the price of a model, reporting false for unknown ones
+func (p Pricing) Price(provider, model string) (ModelPrice, bool) {
+	models := p[provider]
+	if len(models) == 0 && provider == "azure" {
+		models = p["openai"]
+	}
+
+	if price, ok := models[model]; ok {
+		return price, true
+	}
+	best, found := "", false
+	for name := range models {
+		if name != "*" && strings.HasPrefix(model, name) && len(name) > len(best) {
+			best, found = name, true
+		}
+	}
+	if found {
+		return models[best], true
+	}
+	price, ok := models["*"]
+	return price, ok
+}
+
+// Cost estimates what usage cost in USD, reporting false for unknown models
+func (p Pricing) Cost(provider, model string, usage Usage) (float64, bool) {
+	price, ok := p.Price(provider, model)
+	if !ok {
+		return 0, false
+	}
+	return float64(usage.InputTokens)/1000*price.Input + float64(usage.OutputTokens)/1000*price.Output, true
+}
+
+// formatCost renders an estimate for the header, "—" when unknown
+func formatCost(cost float64, known bool) string {
+	switch {
+	case !known:
+		return "—"
+	case cost == 0:
+		return "$0"
+	case cost < 0.01:
+		return fmt.Sprintf("$%.4f", cost)
+	default:
+		return fmt.Sprintf("$%.2f", cost)
+	}
+}
@@ -6,8 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // ChatMessage represents a message in the conversation
@@ -30,24 +36,161 @@ type ToolCall struct {
 type ChatResponse struct {
 	Content   string
 	ToolCalls []ToolCall
+	Model     string // model that produced the reply, as reported by the API
+	Provider  string
+	Usage     Usage
+}
+
+// Usage is the token count a provider reports for one request
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{InputTokens: u.InputTokens + other.InputTokens, OutputTokens: u.OutputTokens + other.OutputTokens}
 }
 
 // Provider interface for AI providers
 type Provider interface {
 	Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatResponse, error)
+
+	// Ping makes the cheapest authenticated call the provider offers so bad
+	// credentials show up on connect rather than on the first message
+	Ping(ctx context.Context) error
+}
+
+// PingTimeout bounds the connection check made on Ctrl+N
+const PingTimeout = 10 * time.Second
+
+// sharedHTTPClient is reused by all providers so connections are pooled
+// across turns instead of re-dialing TCP/TLS for every request
+var sharedHTTPClient = &http.Client{
+	Timeout: 5 * time.Minute,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        20,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// Sampling holds generation settings shared by all providers. Zero values
+// are left out of requests so provider defaults apply.
+type Sampling struct {
+	Temperature float64
+	MaxTokens   int
+}
+
+// SetSampling updates the generation settings
+func (s *Sampling) SetSampling(sampling Sampling) {
+	*s = sampling
+}
+
+// SamplingFromEnv reads default generation settings from AI_TEMPERATURE and AI_MAX_TOKENS
+func SamplingFromEnv() Sampling {
+	sampling := Sampling{}
+	if v, err := strconv.ParseFloat(os.Getenv("AI_TEMPERATURE"), 64); err == nil {
+		sampling.Temperature = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AI_MAX_TOKENS")); err == nil {
+		sampling.MaxTokens = v
+	}
+	return sampling
+}
+
+// ProviderOption customizes a provider built by CreateProvider
+type ProviderOption func(*providerOptions)
+
+type providerOptions struct {
+	client   *http.Client
+	sampling Sampling
+	logger   *slog.Logger
+	model    string
+	limiter  *RateLimiter
+	limited  bool
+}
+
+// WithHTTPClient makes the provider use client instead of the shared one
+func WithHTTPClient(client *http.Client) ProviderOption {
+	return func(o *providerOptions) {
+		o.client = client
+	}
+}
+
+// WithSampling overrides the generation settings read from the environment
+func WithSampling(sampling Sampling) ProviderOption {
+	return func(o *providerOptions) {
+		o.sampling = sampling
+	}
+}
+
+// WithRateLimiter replaces the provider's shared *_RPM limiter, nil
+// disables rate limiting
+func WithRateLimiter(limiter *RateLimiter) ProviderOption {
+	return func(o *providerOptions) {
+		o.limiter = limiter
+		o.limited = true
+	}
+}
+
+// WithModel overrides the provider's default model. OLLAMA_MODEL still
+// takes precedence for Ollama, and Azure always uses its deployment.
+func WithModel(model string) ProviderOption {
+	return func(o *providerOptions) {
+		o.model = model
+	}
+}
+
+// WithLogger makes the provider log requests and responses to logger
+func WithLogger(logger *slog.Logger) ProviderOption {
+	return func(o *providerOptions) {
+		o.logger = logger
+	}
 }
 
 // CreateProvider creates a provider based on type
-func CreateProvider(providerType string) (Provider, error) {
+func CreateProvider(providerType string, opts ...ProviderOption) (Provider, error) {
+	options := providerOptions{
+		client:   sharedHTTPClient,
+		sampling: SamplingFromEnv(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if !options.limited {
+		options.limiter = providerLimiter(providerType)
+	}
+
 	switch providerType {
 	case "openai":
 		apiKey := os.Getenv("OPENAI_API_KEY")
 		if apiKey == "" {
 			return nil, fmt.Errorf("OPENAI_API_KEY not set")
 		}
+		baseURL := os.Getenv("OPENAI_BASE_URL")
+		if baseURL == "" {
+			baseURL = DefaultOpenAIBaseURL
+		}
+		if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid OPENAI_BASE_URL: %q", baseURL)
+		}
 		return &OpenAIProvider{
-			APIKey: apiKey,
-			Model:  "gpt-4-turbo-preview",
+			APIKey:  apiKey,
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Model:   modelOrDefault(options.model, "gpt-4-turbo-preview"),
+			Client:  options.client,
+			Logger:  options.logger,
+			Limiter: options.limiter,
+
+			Sampling: options.sampling,
 		}, nil
 
 	case "anthropic":
@@ -56,14 +199,59 @@ func CreateProvider(providerType string) (Provider, error) {
 			return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
 		}
 		return &AnthropicProvider{
-			APIKey: apiKey,
-			Model:  "claude-3-5-sonnet-20241022",
+			APIKey:  apiKey,
+			Model:   modelOrDefault(options.model, "claude-3-5-sonnet-20241022"),
+			Client:  options.client,
+			Logger:  options.logger,
+			Limiter: options.limiter,
+
+			Sampling: options.sampling,
+		}, nil
+
+	case "azure":
+		endpoint := os.Getenv("AZURE_OPENAI_ENDPOINT")
+		apiKey := os.Getenv("AZURE_OPENAI_KEY")
+		deployment := os.Getenv("AZURE_OPENAI_DEPLOYMENT")
+		if endpoint == "" || apiKey == "" || deployment == "" {
+			return nil, fmt.Errorf("AZURE_OPENAI_ENDPOINT, AZURE_OPENAI_KEY and AZURE_OPENAI_DEPLOYMENT must be set")
+		}
+		apiVersion := os.Getenv("AZURE_OPENAI_API_VERSION")
+		if apiVersion == "" {
+			apiVersion = "2024-02-01"
+		}
+		return &AzureOpenAIProvider{
+			Endpoint:   endpoint,
+			APIKey:     apiKey,
+			Deployment: deployment,
+			APIVersion: apiVersion,
+			Client:     options.client,
+			Logger:     options.logger,
+			Limiter:    options.limiter,
+
+			Sampling: options.sampling,
 		}, nil
 
 	case "ollama":
+		baseURL := os.Getenv("OLLAMA_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+		if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid OLLAMA_BASE_URL: %q", baseURL)
+		}
+		model := os.Getenv("OLLAMA_MODEL")
+		if model == "" {
+			model = modelOrDefault(options.model, "llama3.1")
+		}
+		// The model is checked by Ping when connecting
 		return &OllamaProvider{
-			BaseURL: "http://localhost:11434",
-			Model:   "llama3.1",
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Model:   model,
+			Client:  options.client,
+			Logger:  options.logger,
+			Limiter: options.limiter,
+
+			Sampling: options.sampling,
 		}, nil
 
 	default:
@@ -71,19 +259,54 @@ func CreateProvider(providerType string) (Provider, error) {
 	}
 }
 
-// OpenAIProvider implements Provider for OpenAI
+// modelOrDefault returns model, or def when no model was configured
+func modelOrDefault(model, def string) string {
+	if model == "" {
+		return def
+	}
+	return model
+}
+
+// ProviderModel returns the model (or Azure deployment) a provider is configured with
+func ProviderModel(p Provider) string {
+	switch p := p.(type) {
+	case *OpenAIProvider:
+		return p.Model
+	case *AzureOpenAIProvider:
+		return p.Deployment
+	case *AnthropicProvider:
+		return p.Model
+	case *OllamaProvider:
+		return p.Model
+	}
+	return ""
+}
+
+// DefaultOpenAIBaseURL is used when OPENAI_BASE_URL is not set
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIProvider implements Provider for the OpenAI API and compatible
+// endpoints (LM Studio, OpenRouter, proxies) reached through BaseURL
 type OpenAIProvider struct {
-	APIKey string
-	Model  string
+	APIKey  string
+	BaseURL string // empty means DefaultOpenAIBaseURL
+	Model   string
+	Client  *http.Client
+	Logger  *slog.Logger
+	Limiter *RateLimiter
+	Sampling
 }
 
 type openAIRequest struct {
-	Model    string        `json:"model"`
-	Messages []ChatMessage `json:"messages"`
-	Tools    []interface{} `json:"tools,omitempty"`
+	Model       string        `json:"model,omitempty"`
+	Messages    []ChatMessage `json:"messages"`
+	Tools       []interface{} `json:"tools,omitempty"`
+	Temperature float64       `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
 }
 
 type openAIResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
 			Content   string `json:"content"`
@@ -96,14 +319,119 @@ type openAIResponse struct {
 			} `json:"tool_calls"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 }
 
 func (p *OpenAIProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatResponse, error) {
-	req := openAIRequest{
-		Model:    p.Model,
-		Messages: messages,
+	if err := p.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return chatOpenAICompatible(ctx, p.Client, p.Logger, "openai", p.endpoint("chat/completions"), map[string]string{
+		"Authorization": "Bearer " + p.APIKey,
+	}, openAIRequest{
+		Model:       p.Model,
+		Messages:    messages,
+		Temperature: p.Temperature,
+		MaxTokens:   p.MaxTokens,
+	}, tools)
+}
+
+// endpoint joins path onto the base URL with exactly one slash between them
+func (p *OpenAIProvider) endpoint(path string) string {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
 	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// Ping lists the available models
+func (p *OpenAIProvider) Ping(ctx context.Context) error {
+	return pingProvider(ctx, p.Client, p.Logger, "openai", "GET", p.endpoint("models"), map[string]string{
+		"Authorization": "Bearer " + p.APIKey,
+	}, nil)
+}
+
+// AzureOpenAIProvider implements Provider for Azure OpenAI deployments
+type AzureOpenAIProvider struct {
+	Endpoint   string
+	APIKey     string
+	Deployment string
+	APIVersion string
+	Client     *http.Client
+	Logger     *slog.Logger
+	Limiter    *RateLimiter
+	Sampling
+}
 
+func (p *AzureOpenAIProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatResponse, error) {
+	if err := p.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		strings.TrimSuffix(p.Endpoint, "/"), p.Deployment, p.APIVersion)
+
+	// The deployment determines the model, so none is sent in the body
+	return chatOpenAICompatible(ctx, p.Client, p.Logger, "azure", url, map[string]string{
+		"api-key": p.APIKey,
+	}, openAIRequest{
+		Messages:    messages,
+		Temperature: p.Temperature,
+		MaxTokens:   p.MaxTokens,
+	}, tools)
+}
+
+// Ping lists the models available to the resource
+func (p *AzureOpenAIProvider) Ping(ctx context.Context) error {
+	url := fmt.Sprintf("%s/openai/models?api-version=%s", strings.TrimSuffix(p.Endpoint, "/"), p.APIVersion)
+	return pingProvider(ctx, p.Client, p.Logger, "azure", "GET", url, map[string]string{
+		"api-key": p.APIKey,
+	}, nil)
+}
+
+// httpClient returns client, or the shared client for zero-value providers
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return sharedHTTPClient
+	}
+	return client
+}
+
+// pingProvider sends a connection check request and returns an *APIError
+// for any non-200 response
+func pingProvider(ctx context.Context, client *http.Client, logger *slog.Logger, provider, method, url string, headers map[string]string, body []byte) error {
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	logRequest(logger, provider, httpReq, body)
+
+	resp, err := httpClient(client).Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return logAPIError(logger, newAPIError(provider, resp))
+	}
+	logResponse(logger, provider, resp.StatusCode, "ok")
+	return nil
+}
+
+// chatOpenAICompatible sends a chat completion request in the OpenAI format,
+// shared by OpenAI and Azure OpenAI which differ only in URL and auth headers
+func chatOpenAICompatible(ctx context.Context, client *http.Client, logger *slog.Logger, provider, url string, headers map[string]string, req openAIRequest, tools []Tool) (*ChatResponse, error) {
 	if len(tools) > 0 {
 		req.Tools = make([]interface{}, len(tools))
 		for i, tool := range tools {
@@ -123,27 +451,35 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []ChatMessage, tools
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	logRequest(logger, provider, httpReq, body)
 
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := httpClient(client).Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(body))
+		return nil, logAPIError(logger, newAPIError(provider, resp))
 	}
 
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logResponse(logger, provider, resp.StatusCode, string(respBody))
+
 	var apiResp openAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
 		return nil, err
 	}
 
@@ -152,7 +488,13 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []ChatMessage, tools
 	}
 
 	response := &ChatResponse{
-		Content: apiResp.Choices[0].Message.Content,
+		Content:  apiResp.Choices[0].Message.Content,
+		Model:    apiResp.Model,
+		Provider: provider,
+		Usage:    Usage{InputTokens: apiResp.Usage.PromptTokens, OutputTokens: apiResp.Usage.CompletionTokens},
+	}
+	if response.Model == "" {
+		response.Model = req.Model
 	}
 
 	for _, tc := range apiResp.Choices[0].Message.ToolCalls {
@@ -171,16 +513,35 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []ChatMessage, tools
 
 // AnthropicProvider implements Provider for Anthropic Claude
 type AnthropicProvider struct {
-	APIKey string
-	Model  string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+	Logger  *slog.Logger
+	Limiter *RateLimiter
+	Sampling
 }
 
 func (p *AnthropicProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatResponse, error) {
+	if err := p.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 	// Convert to Anthropic format
+	system, anthropicMessages := toAnthropicMessages(messages)
+	// max_tokens is required by Anthropic
+	maxTokens := p.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
 	req := map[string]interface{}{
 		"model":      p.Model,
-		"max_tokens": 4096,
-		"messages":   messages,
+		"max_tokens": maxTokens,
+		"messages":   anthropicMessages,
+	}
+	if system != "" {
+		req["system"] = system
+	}
+	if p.Temperature != 0 {
+		req["temperature"] = p.Temperature
 	}
 
 	if len(tools) > 0 {
@@ -208,24 +569,38 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []ChatMessage, to
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("x-api-key", p.APIKey)
 	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	logRequest(p.Logger, "anthropic", httpReq, body)
 
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := httpClient(p.Client).Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(body))
+		return nil, logAPIError(p.Logger, newAPIError("anthropic", resp))
 	}
 
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logResponse(p.Logger, "anthropic", resp.StatusCode, string(respBody))
+
 	var apiResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
 		return nil, err
 	}
 
-	response := &ChatResponse{}
+	response := &ChatResponse{Model: p.Model, Provider: "anthropic"}
+	if model, ok := apiResp["model"].(string); ok && model != "" {
+		response.Model = model
+	}
+	if usage, ok := apiResp["usage"].(map[string]interface{}); ok {
+		input, _ := usage["input_tokens"].(float64)
+		output, _ := usage["output_tokens"].(float64)
+		response.Usage = Usage{InputTokens: int(input), OutputTokens: int(output)}
+	}
 
 	content, ok := apiResp["content"].([]interface{})
 	if !ok {
@@ -250,17 +625,129 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []ChatMessage, to
 	return response, nil
 }
 
+// Ping sends a one-token message, Anthropic has no cheaper authenticated call
+func (p *AnthropicProvider) Ping(ctx context.Context) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      p.Model,
+		"max_tokens": 1,
+		"messages":   []map[string]string{{"role": "user", "content": "ping"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return pingProvider(ctx, p.Client, p.Logger, "anthropic", "POST", "https://api.anthropic.com/v1/messages", map[string]string{
+		"x-api-key":         p.APIKey,
+		"anthropic-version": "2023-06-01",
+	}, body)
+}
+
+// toAnthropicMessages converts our messages to the Anthropic Messages API
+// shape: system messages become the top-level system prompt, assistant tool
+// calls become tool_use blocks and tool results become tool_result blocks in
+// a user turn.
+func toAnthropicMessages(messages []ChatMessage) (string, []map[string]interface{}) {
+	var system []string
+	var out []map[string]interface{}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+
+		case "assistant":
+			if len(msg.ToolCalls) == 0 {
+				out = append(out, map[string]interface{}{
+					"role":    "assistant",
+					"content": msg.Content,
+				})
+				continue
+			}
+
+			blocks := []map[string]interface{}{}
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]interface{}{
+					"type": "text",
+					"text": msg.Content,
+				})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := tc.Arguments
+				if input == nil {
+					input = map[string]interface{}{}
+				}
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    tc.ID,
+					"name":  tc.Name,
+					"input": input,
+				})
+			}
+			out = append(out, map[string]interface{}{
+				"role":    "assistant",
+				"content": blocks,
+			})
+
+		case "tool":
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+
+			// Results for the same assistant turn go into a single user turn
+			if len(out) > 0 {
+				last := out[len(out)-1]
+				if blocks, ok := last["content"].([]map[string]interface{}); ok && last["role"] == "user" {
+					last["content"] = append(blocks, block)
+					continue
+				}
+			}
+			out = append(out, map[string]interface{}{
+				"role":    "user",
+				"content": []map[string]interface{}{block},
+			})
+
+		default:
+			out = append(out, map[string]interface{}{
+				"role":    "user",
+				"content": msg.Content,
+			})
+		}
+	}
+
+	return strings.Join(system, "\n\n"), out
+}
+
 // OllamaProvider implements Provider for Ollama local models
 type OllamaProvider struct {
 	BaseURL string
 	Model   string
+	Client  *http.Client
+	Logger  *slog.Logger
+	Limiter *RateLimiter
+	Sampling
 }
 
 func (p *OllamaProvider) Chat(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatResponse, error) {
+	if err := p.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 	req := map[string]interface{}{
 		"model":    p.Model,
 		"messages": messages,
-		"stream":   false,
+		"stream":   true,
+	}
+
+	options := map[string]interface{}{}
+	if p.Temperature != 0 {
+		options["temperature"] = p.Temperature
+	}
+	if p.MaxTokens != 0 {
+		options["num_predict"] = p.MaxTokens
+	}
+	if len(options) > 0 {
+		req["options"] = options
 	}
 
 	if len(tools) > 0 {
@@ -289,42 +776,128 @@ func (p *OllamaProvider) Chat(ctx context.Context, messages []ChatMessage, tools
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	logRequest(p.Logger, "ollama", httpReq, body)
 
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := httpClient(p.Client).Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(body))
+		return nil, logAPIError(p.Logger, newAPIError("ollama", resp))
 	}
 
-	var apiResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+	response, err := parseOllamaStream(resp.Body)
+	if err != nil {
 		return nil, err
 	}
+	response.Model = p.Model
+	response.Provider = "ollama"
+
+	// Log the assembled reply rather than the raw stream chunks
+	if p.Logger != nil {
+		assembled, _ := json.Marshal(map[string]interface{}{
+			"content":    response.Content,
+			"tool_calls": response.ToolCalls,
+		})
+		logResponse(p.Logger, "ollama", resp.StatusCode, string(assembled))
+	}
+	return response, nil
+}
+
+// Ping checks the server is reachable and has the configured model pulled
+func (p *OllamaProvider) Ping(ctx context.Context) error {
+	return p.checkModel(ctx)
+}
+
+// checkModel asks the server for its pulled models via /api/tags and fails
+// with the list of available ones if the configured model is missing
+func (p *OllamaProvider) checkModel(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
 
+	resp, err := httpClient(p.Client).Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("cannot reach Ollama at %s: %w", p.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("ollama", resp)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return err
+	}
+
+	available := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		// Untagged model names refer to ":latest"
+		if m.Name == p.Model || m.Name == p.Model+":latest" {
+			return nil
+		}
+		available = append(available, m.Name)
+	}
+
+	if len(available) == 0 {
+		return fmt.Errorf("model %q not found, no models pulled (try: ollama pull %s)", p.Model, p.Model)
+	}
+	return fmt.Errorf("model %q not found, available: %s", p.Model, strings.Join(available, ", "))
+}
+
+// parseOllamaStream reads Ollama's newline-delimited JSON chunks, accumulating
+// message content until the final "done": true object. A single non-streamed
+// object is handled the same way.
+func parseOllamaStream(r io.Reader) (*ChatResponse, error) {
 	response := &ChatResponse{}
+	decoder := json.NewDecoder(r)
+
+	for {
+		var chunk map[string]interface{}
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
 
-	if message, ok := apiResp["message"].(map[string]interface{}); ok {
-		if content, ok := message["content"].(string); ok {
-			response.Content = content
+		if errMsg, ok := chunk["error"].(string); ok {
+			return nil, &APIError{Provider: "ollama", StatusCode: http.StatusOK, Message: errMsg}
 		}
 
-		if toolCalls, ok := message["tool_calls"].([]interface{}); ok {
-			for _, tc := range toolCalls {
-				tcMap := tc.(map[string]interface{})
-				funcMap := tcMap["function"].(map[string]interface{})
+		if message, ok := chunk["message"].(map[string]interface{}); ok {
+			if content, ok := message["content"].(string); ok {
+				response.Content += content
+			}
 
-				response.ToolCalls = append(response.ToolCalls, ToolCall{
-					ID:        fmt.Sprintf("%v", tcMap["id"]),
-					Name:      funcMap["name"].(string),
-					Arguments: funcMap["arguments"].(map[string]interface{}),
-				})
+			if toolCalls, ok := message["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					tcMap := tc.(map[string]interface{})
+					funcMap := tcMap["function"].(map[string]interface{})
+
+					response.ToolCalls = append(response.ToolCalls, ToolCall{
+						ID:        fmt.Sprintf("%v", tcMap["id"]),
+						Name:      funcMap["name"].(string),
+						Arguments: funcMap["arguments"].(map[string]interface{}),
+					})
+				}
 			}
 		}
+
+		if done, _ := chunk["done"].(bool); done {
+			// Token counts only come with the final chunk
+			input, _ := chunk["prompt_eval_count"].(float64)
+			output, _ := chunk["eval_count"].(float64)
+			response.Usage = Usage{InputTokens: int(input), OutputTokens: int(output)}
+			break
+		}
 	}
 
 	return response, nil
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// roundTripFunc fakes an HTTP transport for providers with a fixed endpoint
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// asJSON marshals and decodes v so it compares like the wire format
+func asJSON(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestToAnthropicMessagesToolRoundTrip(t *testing.T) {
+	messages := []ChatMessage{
+		{Role: "system", Content: "You help with notes."},
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Find my notes on Go"},
+		{Role: "assistant", Content: "Searching.", ToolCalls: []ToolCall{
+			{ID: "toolu_1", Name: "search_obsidian_notes", Arguments: map[string]interface{}{"query": "go"}},
+			{ID: "toolu_2", Name: "get_obsidian_tags"},
+		}},
+		{Role: "tool", ToolCallID: "toolu_1", Content: `[{"path":"Go.md"}]`},
+		{Role: "tool", ToolCallID: "toolu_2", Content: `{"go":3}`},
+		{Role: "assistant", Content: "You have Go.md."},
+	}
+
+	system, out := toAnthropicMessages(messages)
+
+	if system != "You help with notes.\n\nBe brief." {
+		t.Errorf("system = %q", system)
+	}
+
+	want := []interface{}{
+		map[string]interface{}{"role": "user", "content": "Find my notes on Go"},
+		map[string]interface{}{"role": "assistant", "content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "Searching."},
+			map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "search_obsidian_notes", "input": map[string]interface{}{"query": "go"}},
+			map[string]interface{}{"type": "tool_use", "id": "toolu_2", "name": "get_obsidian_tags", "input": map[string]interface{}{}},
+		}},
+		map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": `[{"path":"Go.md"}]`},
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_2", "content": `{"go":3}`},
+		}},
+		map[string]interface{}{"role": "assistant", "content": "You have Go.md."},
+	}
+	if got := asJSON(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("messages =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestAnthropicChatToolRoundTrip(t *testing.T) {
+	var requests []map[string]interface{}
+	responses := []string{
+		`{"model":"claude-test","content":[{"type":"text","text":"Let me look."},{"type":"tool_use","id":"toolu_9","name":"read_obsidian_note","input":{"note_path":"Go.md"}}],"usage":{"input_tokens":10,"output_tokens":5}}`,
+		`{"model":"claude-test","content":[{"type":"text","text":"Go.md is about goroutines."}],"usage":{"input_tokens":20,"output_tokens":7}}`,
+	}
+
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, body)
+		return jsonResponse(http.StatusOK, responses[len(requests)-1]), nil
+	})}
+	provider := &AnthropicProvider{APIKey: "key", Model: "claude-test", Client: client}
+
+	messages := []ChatMessage{
+		{Role: "system", Content: "prompt"},
+		{Role: "user", Content: "What is in Go.md?"},
+	}
+	tools := []Tool{{Name: "read_obsidian_note", Description: "Read a note", Parameters: map[string]interface{}{"type": "object"}}}
+
+	first, err := provider.Chat(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCalls := []ToolCall{{ID: "toolu_9", Name: "read_obsidian_note", Arguments: map[string]interface{}{"note_path": "Go.md"}}}
+	if first.Content != "Let me look." || !reflect.DeepEqual(first.ToolCalls, wantCalls) {
+		t.Fatalf("first response = %q %v, want the tool_use parsed", first.Content, first.ToolCalls)
+	}
+
+	messages = append(messages,
+		ChatMessage{Role: "assistant", Content: first.Content, ToolCalls: first.ToolCalls},
+		ChatMessage{Role: "tool", ToolCallID: "toolu_9", Content: "goroutines"},
+	)
+	second, err := provider.Chat(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Content != "Go.md is about goroutines." || len(second.ToolCalls) != 0 {
+		t.Errorf("second response = %q %v", second.Content, second.ToolCalls)
+	}
+
+	sent := requests[1]
+	if sent["system"] != "prompt" {
+		t.Errorf("system = %v, want the top-level prompt", sent["system"])
+	}
+	wantMessages := []interface{}{
+		map[string]interface{}{"role": "user", "content": "What is in Go.md?"},
+		map[string]interface{}{"role": "assistant", "content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "Let me look."},
+			map[string]interface{}{"type": "tool_use", "id": "toolu_9", "name": "read_obsidian_note", "input": map[string]interface{}{"note_path": "Go.md"}},
+		}},
+		map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_9", "content": "goroutines"},
+		}},
+	}
+	if !reflect.DeepEqual(sent["messages"], wantMessages) {
+		t.Errorf("second request messages =\n%v\nwant\n%v", sent["messages"], wantMessages)
+	}
+	if tools, _ := sent["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("tools = %v, want the tool sent again", sent["tools"])
+	}
+}
+
+func TestSharedClientReusesConnections(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"gpt-test","choices":[{"message":{"content":"hi"}}]}`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "key")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	// A client like the shared one, so other tests' idle connections don't count
+	transport := sharedHTTPClient.Transport.(*http.Transport).Clone()
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Timeout: sharedHTTPClient.Timeout, Transport: transport}
+
+	provider, err := CreateProvider("openai", WithHTTPClient(client), WithRateLimiter(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		response, err := provider.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "hello"}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Content != "hi" {
+			t.Fatalf("content = %q, want hi", response.Content)
+		}
+	}
+
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("two Chat calls opened %d connections, want 1", n)
+	}
+}
+
+func TestOllamaChatResponseShapes(t *testing.T) {
+	cases := []struct {
+		name  string
+		body  string
+		want  string
+		usage Usage
+		calls int
+	}{
+		{
+			name: "streamed",
+			body: `{"message":{"role":"assistant","content":"Hel"},"done":false}
+{"message":{"role":"assistant","content":"lo"},"done":false}
+{"message":{"role":"assistant","content":"!"},"done":true,"prompt_eval_count":12,"eval_count":3}
+`,
+			want:  "Hello!",
+			usage: Usage{InputTokens: 12, OutputTokens: 3},
+		},
+		{
+			name: "single object",
+			body: `{
+  "message": {"role": "assistant", "content": "Hello!"},
+  "done": true,
+  "prompt_eval_count": 8,
+  "eval_count": 2
+}`,
+			want:  "Hello!",
+			usage: Usage{InputTokens: 8, OutputTokens: 2},
+		},
+		{
+			name: "streamed tool call",
+			body: `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_obsidian_tags","arguments":{}}}]},"done":false}
+{"message":{"role":"assistant","content":""},"done":true}
+`,
+			calls: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sent map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/chat" {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				io.WriteString(w, tc.body)
+			}))
+			defer server.Close()
+
+			provider := &OllamaProvider{BaseURL: server.URL, Model: "llama3", Client: server.Client()}
+			response, err := provider.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sent["stream"] != true {
+				t.Errorf("stream = %v, want true", sent["stream"])
+			}
+			if response.Content != tc.want {
+				t.Errorf("content = %q, want %q", response.Content, tc.want)
+			}
+			if response.Usage != tc.usage {
+				t.Errorf("usage = %+v, want %+v", response.Usage, tc.usage)
+			}
+			if len(response.ToolCalls) != tc.calls {
+				t.Errorf("tool calls = %v, want %d", response.ToolCalls, tc.calls)
+			}
+			if response.Model != "llama3" || response.Provider != "ollama" {
+				t.Errorf("model/provider = %s/%s", response.Model, response.Provider)
+			}
+		})
+	}
+}
+
+func TestOllamaChatStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"message":{"content":"par"},"done":false}
+{"error":"model runner crashed"}
+`)
+	}))
+	defer server.Close()
+
+	provider := &OllamaProvider{BaseURL: server.URL, Model: "llama3", Client: server.Client()}
+	_, err := provider.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "model runner crashed" {
+		t.Errorf("err = %v, want an APIError carrying the stream error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitBurst caps how many requests may go out back to back before the
+// limiter starts spacing them at the configured rate
+const RateLimitBurst = 5
+
+// rateLimitEnv names the requests-per-minute variable of each provider
+var rateLimitEnv = map[string]string{
+	"openai":    "OPENAI_RPM",
+	"anthropic": "ANTHROPIC_RPM",
+	"azure":     "AZURE_OPENAI_RPM",
+	"ollama":    "OLLAMA_RPM",
+}
+
+var (
+	providerLimitersMu sync.Mutex
+	providerLimiters   = map[string]*RateLimiter{}
+)
+
+// providerLimiter returns the limiter shared by every provider instance of
+// providerType, so reconnecting doesn't reset the bucket. It is nil (no
+// limit) unless the provider's *_RPM variable is set.
+func providerLimiter(providerType string) *RateLimiter {
+	providerLimitersMu.Lock()
+	defer providerLimitersMu.Unlock()
+
+	if limiter, ok := providerLimiters[providerType]; ok {
+		return limiter
+	}
+
+	var limiter *RateLimiter
+	if rpm := envInt(rateLimitEnv[providerType], 0); rpm > 0 {
+		limiter = NewRateLimiter(rpm, min(rpm, RateLimitBurst))
+	}
+	providerLimiters[providerType] = limiter
+	return limiter
+}
+
+// RateLimiter is a token bucket refilled at a fixed rate per minute. A nil
+// limiter never blocks.
+type RateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	perSec   float64
+	last     time.Time
+}
+
+// NewRateLimiter allows perMinute requests per minute with bursts of up to burst
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		perSec:   float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.perSec)
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.perSec * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// State is what the TUI remembers between runs: the last provider and the
+// models picked with /model. It lives next to config.toml as state.json.
+type State struct {
+	Provider string            `json:"provider,omitempty"`
+	Models   map[string]string `json:"models,omitempty"` // by provider type
+}
+
+// DefaultStatePath is state.json under the user config dir (~/.config/ai-agent on Linux)
+func DefaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ai-agent", "state.json")
+}
+
+// LoadState reads the state file, a missing or unreadable one is an empty state
+func LoadState(path string) State {
+	var state State
+	if path == "" {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}
+	}
+	return state
+}
+
+// Save writes the state file, creating its directory if needed
+func (s State) Save(path string) error {
+	if path == "" {
+		return errors.New("no config dir to save state in")
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Apply restores the saved selection over cfg: the provider, unless
+// keepProvider is set (a -provider flag), and the saved models. Unknown
+// providers are ignored.
+func (s State) Apply(cfg *Config, keepProvider bool) {
+	known := func(providerType string) bool {
+		for _, p := range ProviderTypes {
+			if p == providerType {
+				return true
+			}
+		}
+		return false
+	}
+
+	if s.Provider != "" && !keepProvider && known(s.Provider) {
+		cfg.Provider = s.Provider
+	}
+	for providerType, model := range s.Models {
+		if model != "" && known(providerType) {
+			cfg.SetModel(providerType, model)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTemplatesFolder is where templates are looked up unless the vault's
+// TemplatesFolder or OBSIDIAN_TEMPLATES_FOLDER says otherwise
+const DefaultTemplatesFolder = "Templates"
+
+// templatePlaceholder matches {{name}} and {{name:format}}
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([\w-]+)(?::([^}]*))?\s*\}\}`)
+
+// momentTokens converts the Obsidian (moment.js) date tokens used in
+// {{date:...}} and {{time:...}} to Go layouts. Longer tokens come first.
+var momentTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MMMM", "January",
+	"MMM", "Jan",
+	"MM", "01",
+	"DD", "02",
+	"D", "2",
+	"dddd", "Monday",
+	"ddd", "Mon",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+func (v *ObsidianVault) templatesFolder() string {
+	if v.TemplatesFolder != "" {
+		return v.TemplatesFolder
+	}
+	if folder := os.Getenv("OBSIDIAN_TEMPLATES_FOLDER"); folder != "" {
+		return folder
+	}
+	return DefaultTemplatesFolder
+}
+
+// ListTemplates returns the template names, relative to the templates
+// folder and without .md, in alphabetical order
+func (v *ObsidianVault) ListTemplates() ([]string, error) {
+	root := filepath.Join(v.Path, v.templatesFolder())
+	var names []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".md") {
+			relPath, _ := filepath.Rel(root, path)
+			names = append(names, strings.TrimSuffix(relPath, ".md"))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// CreateNoteFromTemplate creates a note from the named template, replacing
+// {{title}}, {{date}}, {{time}} (optionally with a format, as in
+// {{date:YYYY-MM-DD}}) and any {{var}} given in vars. Placeholders without
+// a value are left as they are. An unknown template is an error listing
+// the available ones.
+func (v *ObsidianVault) CreateNoteFromTemplate(title, templateName, folder string, vars map[string]string) (string, error) {
+	template, err := v.readTemplate(templateName)
+	if err != nil {
+		return "", err
+	}
+
+	content := renderTemplate(template, title, time.Now(), vars)
+	return v.CreateNoteWithMeta(title, content, folder, nil, nil, OnConflictError)
+}
+
+// readTemplate loads a template by name, matching case-insensitively when
+// there is no exact match
+func (v *ObsidianVault) readTemplate(name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".md")
+	names, err := v.ListTemplates()
+	if err != nil {
+		return "", err
+	}
+
+	match := ""
+	for _, candidate := range names {
+		if candidate == name {
+			match = candidate
+			break
+		}
+		if match == "" && strings.EqualFold(candidate, name) {
+			match = candidate
+		}
+	}
+	if match == "" {
+		if len(names) == 0 {
+			return "", fmt.Errorf("unknown template %q: no templates in %s/", name, v.templatesFolder())
+		}
+		return "", fmt.Errorf("unknown template %q, available: %s", name, strings.Join(names, ", "))
+	}
+
+	content, err := os.ReadFile(filepath.Join(v.Path, v.templatesFolder(), match+".md"))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// renderTemplate fills in a template's placeholders. vars take precedence
+// over the built-in title, date and time.
+func renderTemplate(template, title string, now time.Time, vars map[string]string) string {
+	return templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		m := templatePlaceholder.FindStringSubmatch(placeholder)
+		name, format := m[1], strings.TrimSpace(m[2])
+
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		switch name {
+		case "title":
+			return title
+		case "date":
+			if format == "" {
+				format = "YYYY-MM-DD"
+			}
+			return now.Format(momentTokens.Replace(format))
+		case "time":
+			if format == "" {
+				format = "HH:mm"
+			}
+			return now.Format(momentTokens.Replace(format))
+		}
+		return placeholder
+	})
+}
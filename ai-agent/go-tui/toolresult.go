@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Tool result summaries shown in the chat
+const (
+	ToolSummaryTitles = 3  // titles listed for list results
+	ToolSummaryMaxLen = 80 // longest plain-text summary
+)
+
+// describeToolCall renders one executed tool call for the chat: a one-line
+// summary of its result, or the full result JSON when raw is set. The model
+// always receives the full result.
+func describeToolCall(tc ToolCall, raw bool) string {
+	if raw {
+		return fmt.Sprintf("🔧 %s → %s", tc.Name, tc.Result)
+	}
+	return fmt.Sprintf("🔧 %s → %s", tc.Name, summarizeToolResult(tc.Result))
+}
+
+// summarizeToolResult turns a tool result (JSON or an "Error: ..." string)
+// into a short human-readable description
+func summarizeToolResult(result string) string {
+	if strings.HasPrefix(result, "Error:") {
+		return "❌ " + truncateSummary(result)
+	}
+
+	// Results over the size cap end in a marker after the kept JSON
+	if body, marker, ok := strings.Cut(result, "\n[result truncated"); ok {
+		return summarizeToolResult(body) + " [truncated" + marker
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(result), &value); err != nil {
+		return truncateSummary(result)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "no results"
+	case []interface{}:
+		return summarizeList(v)
+	case map[string]interface{}:
+		return summarizeObject(v, len(result))
+	case string:
+		return truncateSummary(v)
+	default:
+		return truncateSummary(result)
+	}
+}
+
+func summarizeList(items []interface{}) string {
+	if len(items) == 0 {
+		return "no results"
+	}
+
+	var titles []string
+	for _, item := range items {
+		if len(titles) == ToolSummaryTitles {
+			break
+		}
+		if obj, ok := item.(map[string]interface{}); ok {
+			if title := resultTitle(obj); title != "" {
+				titles = append(titles, title)
+			}
+		}
+	}
+
+	noun := "results"
+	if len(items) == 1 {
+		noun = "result"
+	}
+	summary := fmt.Sprintf("%d %s", len(items), noun)
+	if len(titles) > 0 {
+		summary += ": " + strings.Join(titles, ", ")
+		if len(items) > len(titles) {
+			summary += ", …"
+		}
+	}
+	return summary
+}
+
+func summarizeObject(obj map[string]interface{}, resultSize int) string {
+	size := resultSize
+	if content, ok := obj["content"].(string); ok {
+		size = len(content)
+	} else if s, ok := obj["size"].(float64); ok {
+		size = int(s)
+	}
+
+	if title := resultTitle(obj); title != "" {
+		return fmt.Sprintf("%s (%s)", title, formatBytes(size))
+	}
+	return fmt.Sprintf("%d fields (%s)", len(obj), formatBytes(size))
+}
+
+// resultTitle picks the most descriptive name field of a result object
+func resultTitle(obj map[string]interface{}) string {
+	for _, key := range []string{"title", "name", "path", "text"} {
+		if s, ok := obj[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func truncateSummary(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > ToolSummaryMaxLen {
+		return truncateUTF8(s, ToolSummaryMaxLen) + "…"
+	}
+	return s
+}
+
+// formatBytes renders a byte count as B, KB or MB
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
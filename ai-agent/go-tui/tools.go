@@ -1,26 +1,53 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"sort"
+	"unicode/utf8"
 )
 
+// DefaultMaxToolResultBytes caps the JSON of one tool result sent back to
+// the model, about 8k tokens
+const DefaultMaxToolResultBytes = 32 * 1024
+
 // Tool represents a tool that can be called by the AI
 type Tool struct {
 	Name        string
 	Description string
 	Parameters  map[string]interface{}
 	Function    func(map[string]interface{}) (interface{}, error)
+
+	// Destructive tools modify the vault and need user confirmation to run
+	Destructive bool
+
+	// Preview optionally describes what a call would change, shown when
+	// asking for confirmation
+	Preview func(map[string]interface{}) (string, error)
+
+	// MaxResultBytes overrides the registry's result cap for this tool,
+	// 0 uses the registry's and a negative value disables it
+	MaxResultBytes int
 }
 
 // ToolRegistry manages available tools
 type ToolRegistry struct {
 	tools map[string]Tool
+
+	// MaxResultBytes caps the JSON of each tool result, 0 or less for no cap
+	MaxResultBytes int
+
+	// Logger receives argument coercions at debug level, nil drops them
+	Logger *slog.Logger
 }
 
-// NewToolRegistry creates a new tool registry
+// NewToolRegistry creates a new tool registry, capping results at
+// AI_MAX_TOOL_RESULT_BYTES (default DefaultMaxToolResultBytes)
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
-		tools: make(map[string]Tool),
+		tools:          make(map[string]Tool),
+		MaxResultBytes: envInt("AI_MAX_TOOL_RESULT_BYTES", DefaultMaxToolResultBytes),
 	}
 }
 
@@ -38,12 +65,99 @@ func (r *ToolRegistry) GetToolDefinitions() []Tool {
 	return tools
 }
 
-// ExecuteTool executes a tool by name
+// Names returns the registered tool names in alphabetical order
+func (r *ToolRegistry) Names() []string {
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Len returns how many tools are registered
+func (r *ToolRegistry) Len() int {
+	return len(r.tools)
+}
+
+// Get returns the named tool
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// IsDestructive reports whether the named tool is marked destructive
+func (r *ToolRegistry) IsDestructive(name string) bool {
+	tool, ok := r.tools[name]
+	return ok && tool.Destructive
+}
+
+// Preview describes what the named tool call would change, or "" when the
+// tool has no preview
+func (r *ToolRegistry) Preview(name string, arguments map[string]interface{}) (string, error) {
+	tool, ok := r.tools[name]
+	if !ok || tool.Preview == nil {
+		return "", nil
+	}
+	return tool.Preview(arguments)
+}
+
+// ExecuteTool executes a tool by name, first coercing arguments of the
+// wrong JSON type to what the tool's schema declares
 func (r *ToolRegistry) ExecuteTool(name string, arguments map[string]interface{}) (interface{}, error) {
 	tool, ok := r.tools[name]
 	if !ok {
 		return nil, fmt.Errorf("tool not found: %s", name)
 	}
 
+	arguments, coercions := coerceArguments(tool.Parameters, arguments)
+	if len(coercions) > 0 && r.Logger != nil {
+		r.Logger.Debug("coerced tool arguments", "tool", name, "coercions", coercions)
+	}
+
 	return tool.Function(arguments)
 }
+
+// ResultJSON encodes a result of the named tool for the model, truncated to
+// the tool's result cap
+func (r *ToolRegistry) ResultJSON(name string, result interface{}) string {
+	limit := r.MaxResultBytes
+	if tool, ok := r.tools[name]; ok && tool.MaxResultBytes != 0 {
+		limit = tool.MaxResultBytes
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	if limit <= 0 || len(resultJSON) <= limit {
+		return string(resultJSON)
+	}
+	return truncateResultJSON(resultJSON, limit)
+}
+
+// truncateResultJSON shortens an oversized result to about limit bytes.
+// Lists keep as many whole leading items as fit and stay valid JSON; other
+// results are cut. A marker saying how much was kept is appended either way.
+func truncateResultJSON(resultJSON []byte, limit int) string {
+	var items []json.RawMessage
+	if err := json.Unmarshal(resultJSON, &items); err == nil {
+		size := len("[]")
+		kept := 0
+		for _, item := range items {
+			if kept > 0 {
+				size++ // comma
+			}
+			size += len(item)
+			if size > limit {
+				break
+			}
+			kept++
+		}
+		keptJSON, _ := json.Marshal(items[:kept])
+		return fmt.Sprintf("%s\n[result truncated, %d of %d items]", keptJSON, kept, len(items))
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(resultJSON[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n[result truncated, %d of %d bytes]", resultJSON[:cut], cut, len(resultJSON))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MaxParallelTools bounds how many tool calls of one response run at once
+const MaxParallelTools = 4
+
+// toolTurn carries one user message through provider calls and tool rounds.
+// It is handed between commands and Update, never touched concurrently.
+type toolTurn struct {
+	chatMessages []ChatMessage
+	tools        []Tool
+	toolRounds   [][]ToolCall
+	trimmed      int
+	usage        Usage // summed over the turn's provider calls
+
+	// Tool calls of the latest response waiting to be approved and run
+	pending  *ChatResponse
+	approved []bool
+	next     int
+}
+
+// toolRequestMsg is sent when the model asked for tools
+type toolRequestMsg struct {
+	turn *toolTurn
+}
+
+// toolsDoneMsg is sent when a tool round has run and the results are ready
+type toolsDoneMsg struct {
+	turn *toolTurn
+}
+
+// chatStep asks the provider for the next response in the turn, ending the
+// turn when the model answers without tools or the round cap is hit
+func (m model) chatStep(turn *toolTurn) tea.Cmd {
+	provider := m.provider
+	registry := m.tools
+	maxToolRounds := m.maxToolRounds
+
+	return func() tea.Msg {
+		response, err := provider.Chat(context.Background(), turn.chatMessages, turn.tools)
+		if err != nil {
+			return errorMsg{err: err}
+		}
+		turn.usage = turn.usage.Add(response.Usage)
+
+		capReached := len(response.ToolCalls) > 0 && len(turn.toolRounds) >= maxToolRounds
+		if len(response.ToolCalls) == 0 || registry == nil || capReached {
+			return responseMsg{
+				content:        response.Content,
+				model:          response.Model,
+				provider:       response.Provider,
+				usage:          turn.usage,
+				toolRounds:     turn.toolRounds,
+				toolCapReached: capReached,
+				trimmed:        turn.trimmed,
+			}
+		}
+
+		turn.pending = response
+		turn.approved = make([]bool, len(response.ToolCalls))
+		turn.next = 0
+		return toolRequestMsg{turn: turn}
+	}
+}
+
+// confirmNext walks the pending tool calls, approving safe ones and stopping
+// at the next destructive one to ask the user. Once every call is decided
+// the round is run.
+func (m model) confirmNext(turn *toolTurn) (model, tea.Cmd) {
+	calls := turn.pending.ToolCalls
+	for ; turn.next < len(calls); turn.next++ {
+		tc := calls[turn.next]
+		if m.autoApprove || !m.tools.IsDestructive(tc.Name) {
+			turn.approved[turn.next] = true
+			continue
+		}
+
+		args, _ := json.Marshal(tc.Arguments)
+		prompt := fmt.Sprintf("⚠️ Allow %s %s? (y/n)", tc.Name, args)
+		if preview, err := m.tools.Preview(tc.Name, tc.Arguments); err != nil {
+			prompt += fmt.Sprintf("\n(preview failed: %v)", err)
+		} else if preview != "" {
+			prompt += "\n" + preview
+		}
+
+		m.confirming = turn
+		m.addSystemMessage(prompt)
+		return m, nil
+	}
+
+	m.confirming = nil
+	return m, m.runTools(turn)
+}
+
+// answerConfirm records the user's decision for the tool call awaiting confirmation
+func (m model) answerConfirm(approved bool) (model, tea.Cmd) {
+	turn := m.confirming
+	turn.approved[turn.next] = approved
+	if !approved {
+		m.addSystemMessage(fmt.Sprintf("Denied %s", turn.pending.ToolCalls[turn.next].Name))
+	}
+	turn.next++
+	return m.confirmNext(turn)
+}
+
+// runTools executes the approved tool calls of the pending response and
+// appends their results to the conversation
+func (m model) runTools(turn *toolTurn) tea.Cmd {
+	registry := m.tools
+
+	return func() tea.Msg {
+		response := turn.pending
+		executeToolCalls(registry, response.ToolCalls, turn.approved)
+		turn.toolRounds = append(turn.toolRounds, response.ToolCalls)
+
+		// Feed the results back for the next round
+		turn.chatMessages = append(turn.chatMessages, ChatMessage{
+			Role:      "assistant",
+			Content:   response.Content,
+			ToolCalls: response.ToolCalls,
+		})
+
+		for _, tc := range response.ToolCalls {
+			turn.chatMessages = append(turn.chatMessages, ChatMessage{
+				Role:       "tool",
+				Content:    tc.Result,
+				ToolCallID: tc.ID,
+			})
+		}
+
+		turn.pending = nil
+		return toolsDoneMsg{turn: turn}
+	}
+}
+
+// executeToolCalls runs the approved calls, storing each result in its own
+// ToolCall. When none of them is destructive they run concurrently, at most
+// MaxParallelTools at a time; otherwise they run one after another in order,
+// so a write is never reordered against the reads around it.
+func executeToolCalls(registry *ToolRegistry, calls []ToolCall, approved []bool) {
+	execute := func(i int) {
+		if !approved[i] {
+			calls[i].Result = "Error: the user denied this tool call"
+			return
+		}
+
+		result, err := registry.ExecuteTool(calls[i].Name, calls[i].Arguments)
+		if err != nil {
+			calls[i].Result = fmt.Sprintf("Error: %v", err)
+		} else {
+			calls[i].Result = registry.ResultJSON(calls[i].Name, result)
+		}
+	}
+
+	parallel := len(calls) > 1
+	for _, tc := range calls {
+		if registry.IsDestructive(tc.Name) {
+			parallel = false
+			break
+		}
+	}
+	if !parallel {
+		for i := range calls {
+			execute(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, MaxParallelTools)
+	for i := range calls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			execute(i)
+		}(i)
+	}
+	wg.Wait()
+}
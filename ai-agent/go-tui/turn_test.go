@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecuteToolCallsMapsConcurrentResults(t *testing.T) {
+	vault := testVault(t, map[string]string{
+		"Go.md":     "Goroutines and channels.\n",
+		"Rust.md":   "Ownership and borrowing.\n",
+		"Python.md": "Generators and asyncio.\n",
+	})
+	registry := NewToolRegistry()
+	RegisterObsidianTools(registry, vault)
+
+	// search_obsidian_notes runs on its own but waits until all three calls
+	// have started, which only happens when they run concurrently
+	search := registry.tools["search_obsidian_notes"]
+	var started sync.WaitGroup
+	started.Add(3)
+	all := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(all)
+	}()
+	inner := search.Function
+	search.Function = func(args map[string]interface{}) (interface{}, error) {
+		started.Done()
+		select {
+		case <-all:
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("the calls did not run concurrently")
+		}
+		return inner(args)
+	}
+	registry.Register(search)
+
+	calls := []ToolCall{
+		{ID: "1", Name: "search_obsidian_notes", Arguments: map[string]interface{}{"query": "channels"}},
+		{ID: "2", Name: "search_obsidian_notes", Arguments: map[string]interface{}{"query": "borrowing"}},
+		{ID: "3", Name: "search_obsidian_notes", Arguments: map[string]interface{}{"query": "asyncio"}},
+	}
+	executeToolCalls(registry, calls, []bool{true, true, true})
+
+	for i, want := range []string{"Go.md", "Rust.md", "Python.md"} {
+		var results []NoteInfo
+		if err := json.Unmarshal([]byte(calls[i].Result), &results); err != nil {
+			t.Fatalf("call %s result %q: %v", calls[i].ID, calls[i].Result, err)
+		}
+		if len(results) != 1 || results[0].Path != want {
+			t.Errorf("call %s got %+v, want %s", calls[i].ID, results, want)
+		}
+	}
+}
+
+func TestExecuteToolCallsSequentialWithDestructive(t *testing.T) {
+	registry := NewToolRegistry()
+
+	var mu sync.Mutex
+	var order []string
+	running, maxRunning := 0, 0
+	record := func(name string) func(map[string]interface{}) (interface{}, error) {
+		return func(args map[string]interface{}) (interface{}, error) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			order = append(order, name+":"+args["id"].(string))
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return "ok " + args["id"].(string), nil
+		}
+	}
+	registry.Register(Tool{Name: "read", Function: record("read")})
+	registry.Register(Tool{Name: "write", Function: record("write"), Destructive: true})
+
+	calls := []ToolCall{
+		{Name: "read", Arguments: map[string]interface{}{"id": "a"}},
+		{Name: "write", Arguments: map[string]interface{}{"id": "b"}},
+		{Name: "read", Arguments: map[string]interface{}{"id": "c"}},
+		{Name: "read", Arguments: map[string]interface{}{"id": "d"}},
+	}
+	executeToolCalls(registry, calls, []bool{true, true, false, true})
+
+	if maxRunning != 1 {
+		t.Errorf("%d calls ran at once, want one at a time with a destructive call", maxRunning)
+	}
+	if got := strings.Join(order, " "); got != "read:a write:b read:d" {
+		t.Errorf("order = %s, want read:a write:b read:d", got)
+	}
+	if !strings.Contains(calls[2].Result, "denied") {
+		t.Errorf("denied call result = %q", calls[2].Result)
+	}
+	for _, i := range []int{0, 1, 3} {
+		if want := fmt.Sprintf("%q", "ok "+calls[i].Arguments["id"].(string)); calls[i].Result != want {
+			t.Errorf("call %d result = %q, want %s", i, calls[i].Result, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+// VaultSummary is a high-level overview of the vault for vault_stats
+type VaultSummary struct {
+	TotalNotes      int    `json:"total_notes"`
+	TotalTags       int    `json:"total_tags"` // distinct hashtags
+	TotalWords      int    `json:"total_words"`
+	BiggestNote     string `json:"biggest_note"`
+	BiggestNoteSize int64  `json:"biggest_note_size"`
+
+	// Notes no other note links to, and internal links that lead nowhere
+	OrphanNotes int `json:"orphan_notes"`
+	DeadLinks   int `json:"dead_links"`
+}
+
+// VaultStats returns the note, distinct tag and word totals and the largest
+// note by size
+func (v *ObsidianVault) VaultStats() (totalNotes int, totalTags int, totalWords int, biggestNote string, err error) {
+	summary, err := v.Summary()
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	return summary.TotalNotes, summary.TotalTags, summary.TotalWords, summary.BiggestNote, nil
+}
+
+// Summary aggregates the note index into a VaultSummary. Only notes that
+// changed since the last scan are read.
+func (v *ObsidianVault) Summary() (*VaultSummary, error) {
+	index, err := v.scanIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &VaultSummary{TotalNotes: len(index)}
+	tags := make(map[string]bool)
+	linked := make(map[string]bool)
+	names := v.linkIndex()
+
+	for path, entry := range index {
+		for tag := range entry.tags {
+			tags[tag] = true
+		}
+		summary.TotalWords += entry.words
+		if entry.size > summary.BiggestNoteSize || (entry.size == summary.BiggestNoteSize && path < summary.BiggestNote) {
+			summary.BiggestNote, summary.BiggestNoteSize = path, entry.size
+		}
+
+		links := append([]Link(nil), entry.links...)
+		v.resolveLinks(path, links, names)
+		for _, link := range links {
+			switch {
+			case link.External:
+			case link.Resolved == "":
+				summary.DeadLinks++
+			case link.Resolved != path:
+				linked[link.Resolved] = true
+			}
+		}
+	}
+	summary.TotalTags = len(tags)
+
+	for path := range index {
+		if !linked[path] {
+			summary.OrphanNotes++
+		}
+	}
+	return summary, nil
+}
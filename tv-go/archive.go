@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveDir is where raw API responses are kept, empty disables archiving
+func archiveDir() string {
+	return os.Getenv("COLLECTOR_ARCHIVE_DIR")
+}
+
+// archiveResponse writes a raw channel/date API body to
+// <COLLECTOR_ARCHIVE_DIR>/<date>/<channel>.json. Failures are logged but never
+// fail the fetch.
+func archiveResponse(channelID, date string, body []byte) {
+	dir := archiveDir()
+	if dir == "" {
+		return
+	}
+
+	dayDir := filepath.Join(dir, date)
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		log.Printf("    ⚠️  Failed to create archive dir: %v", err)
+		return
+	}
+
+	if err := os.WriteFile(filepath.Join(dayDir, channelID+".json"), body, 0644); err != nil {
+		log.Printf("    ⚠️  Failed to archive response: %v", err)
+	}
+}
+
+// cleanupArchive removes archived days older than daysOld, mirroring the
+// program retention of cleanupOldData
+func cleanupArchive(daysOld int) error {
+	dir := archiveDir()
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -daysOld)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		// Only touch directories named like archive dates
+		date, err := time.ParseInLocation("20060102", entry.Name(), time.Local)
+		if err != nil || !date.Before(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 	"github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/tools/types"
 )
@@ -17,8 +25,50 @@ import (
 const (
 	APIBaseURL = "https://telkussa.fi/API"
 	RateLimit  = 1 * time.Second
+
+	// Request header defaults, overridable with TV_USER_AGENT and TV_ACCEPT_LANGUAGE
+	DefaultUserAgent      = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36"
+	DefaultAcceptLanguage = "fi-FI,fi;q=0.9,en;q=0.8"
+
+	// Incremental fetch defaults: today and tomorrow are always re-fetched,
+	// later days are skipped when fetched successfully within the window
+	DefaultRefreshDays = 2
+	FetchSkipWindow    = 72 * time.Hour
+
+	// DefaultFetchTimeout bounds a whole fetch run so a stuck one can't run
+	// into the next night's
+	DefaultFetchTimeout = 30 * time.Minute
 )
 
+// errFetchDeadline is the error message fetch logs get when a run runs out
+// of time
+var errFetchDeadline = errors.New("deadline exceeded")
+
+// FetchOptions controls a FetchAllPrograms run
+type FetchOptions struct {
+	DaysAhead int
+
+	// Fetch every channel/day even if it already has a recent successful log
+	ForceRefresh bool
+
+	// Days starting from today that are always re-fetched
+	RefreshDays int
+
+	// Overall deadline for the run, zero means none
+	Timeout time.Duration
+}
+
+// DefaultFetchOptions fetches daysAhead days, always refreshing the first
+// FETCH_REFRESH_DAYS days (default 2), within FETCH_TIMEOUT seconds
+// (default 30 minutes, 0 disables the deadline)
+func DefaultFetchOptions(daysAhead int) FetchOptions {
+	return FetchOptions{
+		DaysAhead:   daysAhead,
+		RefreshDays: envInt("FETCH_REFRESH_DAYS", DefaultRefreshDays),
+		Timeout:     time.Duration(envInt("FETCH_TIMEOUT", int(DefaultFetchTimeout.Seconds()))) * time.Second,
+	}
+}
+
 type TVProgram struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
@@ -30,79 +80,365 @@ type TVProgram struct {
 	AgeLimit    int    `json:"agelimit"`
 	Channel     int    `json:"channel"`
 	Rating      int    `json:"rating"`
+	Category    string `json:"category"`
 }
 
 type APIChannel struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	ShowOrder int    `json:"showOrder"`
+	LogoURL   string `json:"-"` // set by sources that carry logos
+}
+
+// Program sources, chosen with TV_SOURCE (default telkussa)
+const (
+	SourceTelkussa = "telkussa"
+	SourceXMLTV    = "xmltv"
+)
+
+// ProgramSource is where the collector gets channels and programs from
+type ProgramSource interface {
+	// Name identifies the source in logs
+	Name() string
+
+	// Channels lists the channels the source carries
+	Channels(ctx context.Context) ([]APIChannel, error)
+
+	// Programs returns one channel's programs for date (YYYYMMDD)
+	Programs(ctx context.Context, channelID, date string) ([]TVProgram, error)
+
+	// Delay is the pause between Programs calls the upstream asks for
+	Delay() time.Duration
 }
 
 type TVCollector struct {
 	app    *pocketbase.PocketBase
 	client *http.Client
+
+	// Headers sent with every API request
+	UserAgent      string
+	AcceptLanguage string
+
+	// Source supplies channels and programs, see UseSource
+	Source ProgramSource
+
+	// Collections resolved during the current run, see collection
+	collections map[string]*models.Collection
 }
 
 func NewTVCollector(app *pocketbase.PocketBase) *TVCollector {
-	return &TVCollector{
+	userAgent := os.Getenv("TV_USER_AGENT")
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	acceptLanguage := os.Getenv("TV_ACCEPT_LANGUAGE")
+	if acceptLanguage == "" {
+		acceptLanguage = DefaultAcceptLanguage
+	}
+
+	c := &TVCollector{
 		app: app,
 		client: &http.Client{
 			Timeout: 15 * time.Second,
 		},
+		UserAgent:      userAgent,
+		AcceptLanguage: acceptLanguage,
+	}
+
+	source := os.Getenv("TV_SOURCE")
+	if source == "" {
+		source = SourceTelkussa
+	}
+	if err := c.UseSource(source); err != nil {
+		log.Printf("⚠️  %v, using %s", err, SourceTelkussa)
+		c.UseSource(SourceTelkussa)
+	}
+	return c
+}
+
+// UseSource switches the collector to the named program source. The XMLTV
+// source reads the file or URL in XMLTV_SOURCE.
+func (c *TVCollector) UseSource(name string) error {
+	switch name {
+	case SourceTelkussa:
+		c.Source = &telkussaSource{c: c}
+	case SourceXMLTV:
+		c.Source = newXMLTVSource(c, os.Getenv("XMLTV_SOURCE"))
+	default:
+		return fmt.Errorf("unknown program source %q, expected %s or %s", name, SourceTelkussa, SourceXMLTV)
+	}
+	return nil
+}
+
+// newAPIRequest builds a GET request to the API with the collector's headers
+func (c *TVCollector) newAPIRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", c.AcceptLanguage)
+
+	return req, nil
+}
+
+// beginRun drops the collections cached by an earlier run, so schema
+// changes made between runs are picked up
+func (c *TVCollector) beginRun() {
+	c.collections = nil
+}
+
+// collection returns the named collection, looking it up only once per
+// run. A collector runs one job at a time, so the cache is not locked.
+func (c *TVCollector) collection(name string) (*models.Collection, error) {
+	if collection, ok := c.collections[name]; ok {
+		return collection, nil
+	}
+
+	collection, err := c.app.Dao().FindCollectionByNameOrId(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.collections == nil {
+		c.collections = make(map[string]*models.Collection)
+	}
+	c.collections[name] = collection
+	return collection, nil
+}
+
+// findRecordById is dao.FindRecordById for an already resolved collection,
+// saving its lookup on every call
+func findRecordById(dao *daos.Dao, collection *models.Collection, id string) (*models.Record, error) {
+	record := &models.Record{}
+	err := dao.RecordQuery(collection).
+		AndWhere(dbx.HashExp{collection.Name + ".id": id}).
+		Limit(1).
+		One(record)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// FetchSummary describes one FetchAllPrograms run
+type FetchSummary struct {
+	ChannelsProcessed int           `json:"channels_processed"` // channel/days fetched, not counting skipped ones
+	ProgramsStored    int           `json:"programs_stored"`    // created + updated
+	Created           int           `json:"created"`
+	Updated           int           `json:"updated"`
+	Unchanged         int           `json:"unchanged"`
+	Skipped           int           `json:"skipped"`
+	Failed            int           `json:"failed"`
+	DeadlineExceeded  bool          `json:"deadline_exceeded"` // the run hit FetchOptions.Timeout
+	Duration          time.Duration `json:"-"`
+}
+
+// LastFetch remembers the outcome of the most recent FetchAllPrograms run
+type LastFetch struct {
+	mu         sync.Mutex
+	summary    *FetchSummary
+	err        error
+	finishedAt time.Time
+}
+
+// lastFetch is reported by /api/admin/fetch/summary
+var lastFetch = &LastFetch{}
+
+func (l *LastFetch) Set(summary FetchSummary, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.summary, l.err, l.finishedAt = &summary, err, time.Now()
+}
+
+// Get returns the last run's summary, when it finished and its error. The
+// summary is nil if no fetch has completed since startup.
+func (l *LastFetch) Get() (*FetchSummary, time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.summary, l.finishedAt, l.err
+}
+
+// FetchState is the progress of the running FetchAllPrograms call
+type FetchState struct {
+	Running        bool
+	StartedAt      time.Time
+	ChannelsDone   int // channel/days done, including skipped ones
+	ChannelsTotal  int // active channels times days to fetch
+	CurrentChannel string
+}
+
+// FetchProgress tracks the FetchState of the fetch in flight
+type FetchProgress struct {
+	mu    sync.Mutex
+	state FetchState
+}
+
+// fetchProgress is reported by /api/admin/trigger/status
+var fetchProgress = &FetchProgress{}
+
+func (p *FetchProgress) start(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = FetchState{Running: true, StartedAt: time.Now(), ChannelsTotal: total}
+}
+
+// channel marks name as the channel being fetched
+func (p *FetchProgress) channel(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.CurrentChannel = name
+}
+
+// done counts one more channel/day as finished
+func (p *FetchProgress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.ChannelsDone++
+	p.state.CurrentChannel = ""
+}
+
+// reset clears the state once the fetch has finished
+func (p *FetchProgress) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = FetchState{}
+}
+
+// Get returns a copy of the current state
+func (p *FetchProgress) Get() FetchState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.state
+}
+
+// LogAttrs returns the summary as slog key/value pairs
+func (s FetchSummary) LogAttrs() []any {
+	return []any{
+		"channels_processed", s.ChannelsProcessed,
+		"programs_stored", s.ProgramsStored,
+		"created", s.Created,
+		"updated", s.Updated,
+		"unchanged", s.Unchanged,
+		"skipped", s.Skipped,
+		"failed", s.Failed,
+		"deadline_exceeded", s.DeadlineExceeded,
+		"duration_ms", s.Duration.Milliseconds(),
 	}
 }
 
-func (c *TVCollector) FetchAllPrograms(daysAhead int) error {
+// FetchAllPrograms fetches programs for all active channels for today plus
+// opts.DaysAhead days, skipping channel/days that were already collected
+// unless opts.ForceRefresh is set. It stops between channels when ctx is
+// canceled or opts.Timeout runs out; a nil ctx means context.Background().
+// The summary covers the work done so far even when an error is returned.
+func (c *TVCollector) FetchAllPrograms(ctx context.Context, opts FetchOptions) (summary FetchSummary, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	started := time.Now()
+	c.beginRun()
+	defer func() {
+		summary.Duration = time.Since(started)
+		lastFetch.Set(summary, err)
+		fetchProgress.reset()
+		c.beginRun()
+	}()
+
 	// Get active channels
 	channels := []*models.Record{}
-	err := c.app.Dao().RecordQuery("channels").
+	err = c.app.Dao().RecordQuery("channels").
 		AndWhere(c.app.Dao().DB().NewExp("active = {:active}", map[string]any{"active": true})).
 		OrderBy("show_order ASC").
 		All(&channels)
 
 	if err != nil {
-		return fmt.Errorf("failed to fetch channels: %w", err)
+		return summary, fmt.Errorf("failed to fetch channels: %w", err)
 	}
 
-	log.Printf("📊 Fetching programs for %d active channels", len(channels))
+	log.Printf("📊 Fetching programs for %d active channels from %s", len(channels), c.Source.Name())
+	fetchProgress.start(len(channels) * (opts.DaysAhead + 1))
+
+	complete := map[string]bool{}
+	if !opts.ForceRefresh {
+		complete, err = c.recentlyFetched(FetchSkipWindow)
+		if err != nil {
+			return summary, fmt.Errorf("failed to read fetch logs: %w", err)
+		}
+	}
 
 	// Fetch programs for today + N days ahead
 	today := time.Now()
 
-	for dayOffset := 0; dayOffset <= daysAhead; dayOffset++ {
+	for dayOffset := 0; dayOffset <= opts.DaysAhead; dayOffset++ {
+		if ctx.Err() != nil {
+			return summary, c.fetchStopped(ctx, opts, &summary)
+		}
+
 		targetDate := today.AddDate(0, 0, dayOffset)
 		dateStr := targetDate.Format("20060102")
 
 		log.Printf("📅 Fetching programs for %s", targetDate.Format("2006-01-02"))
 
 		for _, channel := range channels {
+			if ctx.Err() != nil {
+				return summary, c.fetchStopped(ctx, opts, &summary)
+			}
+
 			channelID := channel.Id
 			channelName := channel.GetString("name")
 
+			if dayOffset >= opts.RefreshDays && complete[channelID+"/"+dateStr] {
+				summary.Skipped++
+				fetchProgress.done()
+				continue
+			}
+
+			summary.ChannelsProcessed++
+			fetchProgress.channel(channelName)
 			startTime := time.Now()
 
-			// Fetch programs from API
-			programs, err := c.fetchChannelPrograms(channelID, dateStr)
+			// Fetch programs from the source
+			programs, err := c.Source.Programs(ctx, channelID, dateStr)
 			duration := time.Since(startTime).Milliseconds()
 
 			if err != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					err = errFetchDeadline
+				}
 				log.Printf("  ⚠️  %s: %v", channelName, err)
-				c.logFetch(channelID, dateStr, false, 0, err.Error(), int(duration))
+				c.logFetch(channelID, dateStr, false, storeCounts{}, err.Error(), int(duration))
+				summary.Failed++
+				fetchProgress.done()
 				continue
 			}
 
-			// Store programs
-			stored := 0
-			seriesMap := make(map[int]string)
+			// Store programs in one transaction, a failure rolls back just this batch
+			counts, err := c.storePrograms(programs, channelID)
+			if err != nil {
+				log.Printf("  ⚠️  %s: storing programs failed, batch rolled back: %v", channelName, err)
+				c.logFetch(channelID, dateStr, false, storeCounts{}, err.Error(), int(duration))
+				summary.Failed++
+				fetchProgress.done()
+				continue
+			}
 
+			seriesMap := make(map[int]string)
 			for _, prog := range programs {
-				if err := c.storeProgram(prog, channelID); err != nil {
-					log.Printf("    ⚠️  Failed to store program: %v", err)
-				} else {
-					stored++
-				}
-
-				// Track series
 				if prog.SeriesID > 0 {
 					seriesMap[prog.SeriesID] = prog.Name
 				}
@@ -113,30 +449,117 @@ func (c *TVCollector) FetchAllPrograms(daysAhead int) error {
 				c.updateSeries(seriesID, name)
 			}
 
-			log.Printf("  ✅ %s: %d programs stored", channelName, stored)
+			log.Printf("  ✅ %s: %d programs (%d new, %d updated, %d unchanged)",
+				channelName, counts.Total(), counts.Created, counts.Updated, counts.Unchanged)
+			summary.add(counts)
 
 			// Log success
-			c.logFetch(channelID, dateStr, true, stored, "", int(duration))
+			c.logFetch(channelID, dateStr, true, counts, "", int(duration))
+			fetchProgress.done()
 
 			// Rate limiting
-			time.Sleep(RateLimit)
+			if delay := c.Source.Delay(); delay > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(delay):
+				}
+			}
 		}
 	}
 
-	return nil
+	// The last channel may have been cut short
+	if ctx.Err() != nil {
+		return summary, c.fetchStopped(ctx, opts, &summary)
+	}
+
+	if summary.Skipped > 0 {
+		log.Printf("⏭️  Skipped %d channel/days already fetched", summary.Skipped)
+	}
+	log.Printf("📦 Programs: %d new, %d updated, %d unchanged", summary.Created, summary.Updated, summary.Unchanged)
+
+	// New data landed, make sure stats reflect it right away
+	statsCache.Invalidate()
+	upcomingCounts.Invalidate()
+
+	return summary, nil
 }
 
-func (c *TVCollector) fetchChannelPrograms(channelID, date string) ([]TVProgram, error) {
-	url := fmt.Sprintf("%s/Channel/%s/%s", APIBaseURL, channelID, date)
+// fetchStopped ends a run whose ctx is done. A run that ran out of time logs
+// its partial summary and a channel-less "deadline exceeded" fetch log, so it
+// is told apart from a cancel and from ordinary channel failures.
+func (c *TVCollector) fetchStopped(ctx context.Context, opts FetchOptions, summary *FetchSummary) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("fetch canceled: %w", ctx.Err())
+	}
+
+	summary.DeadlineExceeded = true
+	log.Printf("⏰ Fetch deadline of %s exceeded, stopping: %d channel/days fetched, %d failed, %d skipped",
+		opts.Timeout, summary.ChannelsProcessed, summary.Failed, summary.Skipped)
+	log.Printf("📦 Programs: %d new, %d updated, %d unchanged", summary.Created, summary.Updated, summary.Unchanged)
+	c.logFetch("", time.Now().Format("20060102"), false, storeCounts{Created: summary.Created, Updated: summary.Updated, Unchanged: summary.Unchanged}, errFetchDeadline.Error(), int(opts.Timeout.Milliseconds()))
+
+	// Whatever was stored is live, same as after a full run
+	statsCache.Invalidate()
+	upcomingCounts.Invalidate()
 
-	req, err := http.NewRequest("GET", url, nil)
+	return fmt.Errorf("%w after %s", errFetchDeadline, opts.Timeout)
+}
+
+// add folds one channel/day's store counts into the summary
+func (s *FetchSummary) add(counts storeCounts) {
+	s.Created += counts.Created
+	s.Updated += counts.Updated
+	s.Unchanged += counts.Unchanged
+	s.ProgramsStored += counts.Created + counts.Updated
+}
+
+// recentlyFetched returns the "channel/YYYYMMDD" pairs with a successful
+// fetch log newer than window
+func (c *TVCollector) recentlyFetched(window time.Duration) (map[string]bool, error) {
+	since, err := types.ParseDateTime(time.Now().Add(-window))
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Accept-Language", "fi-FI,fi;q=0.9,en;q=0.8")
+	var rows []struct {
+		Channel    string `db:"channel"`
+		TargetDate string `db:"target_date"`
+	}
+	err = c.app.Dao().DB().
+		Select("channel", "target_date").
+		Distinct(true).
+		From("fetch_logs").
+		Where(dbx.HashExp{"success": true}).
+		AndWhere(dbx.NewExp("created >= {:since}", dbx.Params{"since": since.String()})).
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	complete := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		complete[row.Channel+"/"+row.TargetDate] = true
+	}
+	return complete, nil
+}
+
+// telkussaSource is the telkussa.fi JSON API
+type telkussaSource struct {
+	c *TVCollector
+}
+
+func (s *telkussaSource) Name() string { return SourceTelkussa }
+
+func (s *telkussaSource) Delay() time.Duration { return RateLimit }
+
+func (s *telkussaSource) Programs(ctx context.Context, channelID, date string) ([]TVProgram, error) {
+	c := s.c
+	url := fmt.Sprintf("%s/Channel/%s/%s", APIBaseURL, channelID, date)
+
+	req, err := c.newAPIRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -153,28 +576,123 @@ func (c *TVCollector) fetchChannelPrograms(channelID, date string) ([]TVProgram,
 		return nil, err
 	}
 
-	var programs []TVProgram
-	if err := json.Unmarshal(body, &programs); err != nil {
+	// Keep the raw body before parsing so parse bugs can be reproduced offline
+	archiveResponse(channelID, date, body)
+
+	programs, err := parseProgramsResponse(body)
+	if err != nil {
 		return nil, err
 	}
+	return programs, nil
+}
+
+// parseProgramsResponse decodes a telkussa programs body. The API answers
+// an array, but for a channel/day without data it may send an empty body,
+// null, {} or an error object such as {"error": "..."}; those are no
+// programs rather than a failure. Other objects and malformed JSON are
+// errors.
+func parseProgramsResponse(body []byte) ([]TVProgram, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return []TVProgram{}, nil
+	}
+
+	if trimmed[0] == '{' {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &object); err != nil {
+			return nil, fmt.Errorf("malformed programs response: %w", err)
+		}
+		for key := range object {
+			switch strings.ToLower(key) {
+			case "error", "errors", "message", "status":
+			default:
+				return nil, fmt.Errorf("unexpected programs response object with key %q", key)
+			}
+		}
+		return []TVProgram{}, nil
+	}
 
+	var programs []TVProgram
+	if err := json.Unmarshal(trimmed, &programs); err != nil {
+		return nil, fmt.Errorf("malformed programs response: %w", err)
+	}
 	return programs, nil
 }
 
-func (c *TVCollector) storeProgram(prog TVProgram, channelID string) error {
-	collection, err := c.app.Dao().FindCollectionByNameOrId("programs")
+// storeOutcome is what storeProgram did with one program
+type storeOutcome int
+
+const (
+	programCreated storeOutcome = iota
+	programUpdated
+	programUnchanged
+)
+
+// storeCounts tallies store outcomes for a batch of programs
+type storeCounts struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+func (s *storeCounts) add(outcome storeOutcome) {
+	switch outcome {
+	case programCreated:
+		s.Created++
+	case programUpdated:
+		s.Updated++
+	case programUnchanged:
+		s.Unchanged++
+	}
+}
+
+// Total is the number of programs seen, written or not
+func (s storeCounts) Total() int {
+	return s.Created + s.Updated + s.Unchanged
+}
+
+// storePrograms saves one channel/day of programs in a single transaction so
+// SQLite commits them together. Nothing is stored if any program fails.
+func (c *TVCollector) storePrograms(programs []TVProgram, channelID string) (storeCounts, error) {
+	var counts storeCounts
+
+	collection, err := c.collection("programs")
 	if err != nil {
-		return err
+		return counts, err
 	}
 
+	err = c.app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		for _, prog := range programs {
+			outcome, err := storeProgram(txDao, collection, prog, channelID)
+			if err != nil {
+				return fmt.Errorf("program %d: %w", prog.ID, err)
+			}
+			counts.add(outcome)
+		}
+		return nil
+	})
+	if err != nil {
+		return storeCounts{}, err
+	}
+
+	return counts, nil
+}
+
+func storeProgram(dao *daos.Dao, collection *models.Collection, prog TVProgram, channelID string) (storeOutcome, error) {
 	programID := strconv.Itoa(prog.ID)
 
 	// Check if program already exists
-	existingRecord, _ := c.app.Dao().FindRecordById("programs", programID)
+	existingRecord, _ := findRecordById(dao, collection, programID)
 
 	var record *models.Record
+	outcome := programCreated
 	if existingRecord != nil {
+		// Re-fetches of stable days mostly return identical data, skip the write
+		if programMatches(existingRecord, prog, channelID) {
+			return programUnchanged, nil
+		}
 		record = existingRecord
+		outcome = programUpdated
 	} else {
 		record = models.NewRecord(collection)
 		record.SetId(programID)
@@ -198,16 +716,39 @@ func (c *TVCollector) storeProgram(prog TVProgram, channelID string) error {
 	record.Set("age_limit", prog.AgeLimit)
 	record.Set("rating", prog.Rating)
 	record.Set("is_series", prog.SeriesID > 0)
+	record.Set("category", prog.Category)
 
 	if prog.SeriesID > 0 {
 		record.Set("series", strconv.Itoa(prog.SeriesID))
 	}
 
-	return c.app.Dao().SaveRecord(record)
+	if err := dao.SaveRecord(record); err != nil {
+		return outcome, err
+	}
+	return outcome, nil
+}
+
+// programMatches reports whether record already holds every field storeProgram would write
+func programMatches(record *models.Record, prog TVProgram, channelID string) bool {
+	if prog.SeriesID > 0 && record.GetString("series") != strconv.Itoa(prog.SeriesID) {
+		return false
+	}
+
+	return record.GetString("channel") == channelID &&
+		record.GetString("name") == prog.Name &&
+		record.GetString("episode") == prog.Episode &&
+		record.GetString("description") == prog.Description &&
+		record.GetDateTime("start_time").Time().Unix() == prog.Start &&
+		record.GetDateTime("end_time").Time().Unix() == prog.Stop &&
+		record.GetInt("duration") == int((prog.Stop-prog.Start)/60) &&
+		record.GetInt("age_limit") == prog.AgeLimit &&
+		record.GetInt("rating") == prog.Rating &&
+		record.GetBool("is_series") == (prog.SeriesID > 0) &&
+		record.GetString("category") == prog.Category
 }
 
 func (c *TVCollector) updateSeries(seriesID int, name string) error {
-	collection, err := c.app.Dao().FindCollectionByNameOrId("series")
+	collection, err := c.collection("series")
 	if err != nil {
 		return err
 	}
@@ -215,7 +756,7 @@ func (c *TVCollector) updateSeries(seriesID int, name string) error {
 	seriesIDStr := strconv.Itoa(seriesID)
 
 	// Check if series exists
-	existingRecord, _ := c.app.Dao().FindRecordById("series", seriesIDStr)
+	existingRecord, _ := findRecordById(c.app.Dao(), collection, seriesIDStr)
 
 	var record *models.Record
 	if existingRecord != nil {
@@ -235,8 +776,8 @@ func (c *TVCollector) updateSeries(seriesID int, name string) error {
 	return c.app.Dao().SaveRecord(record)
 }
 
-func (c *TVCollector) logFetch(channelID, targetDate string, success bool, count int, errorMsg string, durationMs int) error {
-	collection, err := c.app.Dao().FindCollectionByNameOrId("fetch_logs")
+func (c *TVCollector) logFetch(channelID, targetDate string, success bool, counts storeCounts, errorMsg string, durationMs int) error {
+	collection, err := c.collection("fetch_logs")
 	if err != nil {
 		return err
 	}
@@ -248,73 +789,182 @@ func (c *TVCollector) logFetch(channelID, targetDate string, success bool, count
 	}
 	record.Set("target_date", targetDate)
 	record.Set("success", success)
-	record.Set("programs_count", count)
+	record.Set("programs_count", counts.Total())
+	record.Set("programs_created", counts.Created)
+	record.Set("programs_updated", counts.Updated)
+	record.Set("programs_unchanged", counts.Unchanged)
 	record.Set("error_message", errorMsg)
 	record.Set("duration_ms", durationMs)
 
 	return c.app.Dao().SaveRecord(record)
 }
 
-func (c *TVCollector) UpdateChannelList() error {
-	url := fmt.Sprintf("%s/Channels", APIBaseURL)
+// UpdateChannelList refreshes the channels collection from the program
+// source. A nil ctx means context.Background().
+func (c *TVCollector) UpdateChannelList(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.beginRun()
+	defer c.beginRun()
 
-	req, err := http.NewRequest("GET", url, nil)
+	channels, err := c.Source.Channels(ctx)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36")
-	req.Header.Set("Accept", "application/json")
+	log.Printf("📡 Found %d channels in %s", len(channels), c.Source.Name())
 
-	resp, err := c.client.Do(req)
+	collection, err := c.collection("channels")
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
-	}
 
-	body, err := io.ReadAll(resp.Body)
+	// Active channels by normalized name, to catch IDs the API reassigned
+	activeRecords := []*models.Record{}
+	err = c.app.Dao().RecordQuery(collection).
+		AndWhere(dbx.HashExp{"active": true}).
+		All(&activeRecords)
 	if err != nil {
 		return err
 	}
-
-	var channels []APIChannel
-	if err := json.Unmarshal(body, &channels); err != nil {
-		return err
+	activeByName := make(map[string][]*models.Record)
+	for _, record := range activeRecords {
+		name := normalizeChannelName(record.GetString("name"))
+		activeByName[name] = append(activeByName[name], record)
 	}
 
-	log.Printf("📡 Found %d channels in API", len(channels))
+	for _, ch := range channels {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("channel update canceled: %w", err)
+		}
 
-	collection, err := c.app.Dao().FindCollectionByNameOrId("channels")
-	if err != nil {
-		return err
+		channelID := strconv.Itoa(ch.ID)
+		name := normalizeChannelName(ch.Name)
+
+		existingRecord, _ := findRecordById(c.app.Dao(), collection, channelID)
+		record, retire, kept := mergeChannel(collection, existingRecord, activeByName[name], ch)
+
+		if err := c.app.Dao().SaveRecord(record); err != nil {
+			log.Printf("  ⚠️  Failed to save channel %s: %v", ch.Name, err)
+			continue
+		}
+
+		// Retire older active rows of the same channel
+		for _, old := range retire {
+			old.Set("active", false)
+			if err := c.app.Dao().SaveRecord(old); err != nil {
+				log.Printf("  ⚠️  Failed to deactivate duplicate channel %s (%s): %v", ch.Name, old.Id, err)
+				continue
+			}
+			log.Printf("  🔀 Merged channel %s: ID %s replaced by %s", ch.Name, old.Id, channelID)
+		}
+		for _, old := range kept {
+			log.Printf("  🔒 Channel %s: duplicate ID %s has manual_override, left active", ch.Name, old.Id)
+		}
+		if record.GetBool("active") {
+			kept = append([]*models.Record{record}, kept...)
+		}
+		activeByName[name] = kept
 	}
 
-	for _, ch := range channels {
-		channelID := strconv.Itoa(ch.ID)
+	log.Printf("✅ Channel list updated")
 
-		existingRecord, _ := c.app.Dao().FindRecordById("channels", channelID)
+	active := make([]*models.Record, 0, len(activeByName))
+	for _, records := range activeByName {
+		active = append(active, records...)
+	}
+	logoCache.Refresh(ctx, c.app, active)
+	return nil
+}
 
-		var record *models.Record
-		if existingRecord != nil {
-			record = existingRecord
-		} else {
-			record = models.NewRecord(collection)
-			record.SetId(channelID)
-			record.Set("active", true) // New channels are active by default
+// mergeChannel applies an API channel to its record: existing is the record
+// under the channel's ID, nil for a new ID, and previous are the active
+// records with the same normalized name. A new ID of a known channel
+// inherits its curated fields. It returns the record to save, the other
+// rows of the channel to deactivate, and the ones kept active because an
+// admin set manual_override on them. An inactive record that retires
+// others is reactivated unless overridden. Overridden records also keep
+// their show_order; name and a missing logo_url are always refreshed.
+func mergeChannel(collection *models.Collection, existing *models.Record, previous []*models.Record, ch APIChannel) (*models.Record, []*models.Record, []*models.Record) {
+	channelID := strconv.Itoa(ch.ID)
+
+	record := existing
+	if record == nil {
+		record = models.NewRecord(collection)
+		record.SetId(channelID)
+		record.Set("active", true) // New channels are active by default
+
+		// Same channel under a new ID keeps its curated fields
+		if len(previous) > 0 {
+			record.Set("category", previous[0].GetString("category"))
+			record.Set("logo_url", previous[0].GetString("logo_url"))
+			if previous[0].GetBool("manual_override") {
+				record.Set("manual_override", true)
+				record.Set("show_order", previous[0].GetInt("show_order"))
+			}
 		}
+	}
 
-		record.Set("name", ch.Name)
+	record.Set("name", ch.Name)
+	if ch.LogoURL != "" && record.GetString("logo_url") == "" {
+		record.Set("logo_url", ch.LogoURL)
+	}
+	// Admin-curated channels keep their own ordering
+	if !record.GetBool("manual_override") {
 		record.Set("show_order", ch.ShowOrder)
+	}
 
-		if err := c.app.Dao().SaveRecord(record); err != nil {
-			log.Printf("  ⚠️  Failed to save channel %s: %v", ch.Name, err)
+	var retire, kept []*models.Record
+	for _, old := range previous {
+		switch {
+		case old.Id == channelID:
+		case old.GetBool("manual_override"):
+			kept = append(kept, old)
+		default:
+			retire = append(retire, old)
 		}
 	}
 
-	log.Printf("✅ Channel list updated")
-	return nil
+	// An ID the API switched back to takes over from the rows it retires
+	if len(retire) > 0 && !record.GetBool("manual_override") {
+		record.Set("active", true)
+	}
+	return record, retire, kept
+}
+
+func (s *telkussaSource) Channels(ctx context.Context) ([]APIChannel, error) {
+	c := s.c
+	url := fmt.Sprintf("%s/Channels", APIBaseURL)
+
+	req, err := c.newAPIRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []APIChannel
+	if err := json.Unmarshal(body, &channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// normalizeChannelName folds case and whitespace so renumbered channels match
+func normalizeChannelName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
 }
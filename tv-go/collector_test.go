@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+func channelRecord(collection *models.Collection, id, name string, showOrder int, override bool) *models.Record {
+	record := models.NewRecord(collection)
+	record.SetId(id)
+	record.Set("name", name)
+	record.Set("active", true)
+	record.Set("category", "public")
+	record.Set("show_order", showOrder)
+	record.Set("manual_override", override)
+	return record
+}
+
+func TestMergeChannelReassignedID(t *testing.T) {
+	collection := &models.Collection{Name: "channels"}
+	old := channelRecord(collection, "1", "Yle TV1", 1, false)
+
+	record, retire, kept := mergeChannel(collection, nil, []*models.Record{old}, APIChannel{ID: 101, Name: "YLE  TV1", ShowOrder: 5})
+	for _, r := range retire {
+		r.Set("active", false)
+	}
+
+	if record.Id != "101" || !record.GetBool("active") {
+		t.Fatalf("record = %s active=%v, want active 101", record.Id, record.GetBool("active"))
+	}
+	if record.GetString("category") != "public" {
+		t.Errorf("category = %q, want it inherited from the old ID", record.GetString("category"))
+	}
+	if len(kept) != 0 {
+		t.Errorf("kept = %d, want 0", len(kept))
+	}
+
+	active := 0
+	for _, r := range []*models.Record{old, record} {
+		if r.GetBool("active") {
+			active++
+		}
+	}
+	if active != 1 {
+		t.Errorf("%d active rows of the channel, want 1", active)
+	}
+}
+
+func TestMergeChannelLeavesOverriddenDuplicateActive(t *testing.T) {
+	collection := &models.Collection{Name: "channels"}
+	old := channelRecord(collection, "1", "Yle TV1", 9, true)
+
+	record, retire, kept := mergeChannel(collection, nil, []*models.Record{old}, APIChannel{ID: 101, Name: "Yle TV1", ShowOrder: 5})
+
+	if len(retire) != 0 {
+		t.Errorf("retire = %d, want the overridden row left alone", len(retire))
+	}
+	if len(kept) != 1 || kept[0] != old {
+		t.Errorf("kept = %v, want the overridden row", kept)
+	}
+	if !record.GetBool("manual_override") || record.GetInt("show_order") != 9 {
+		t.Errorf("new ID override=%v show_order=%d, want the curated 9 inherited",
+			record.GetBool("manual_override"), record.GetInt("show_order"))
+	}
+}
+
+func TestMergeChannelOverrideKeepsCuratedFields(t *testing.T) {
+	collection := &models.Collection{Name: "channels"}
+	existing := channelRecord(collection, "1", "Yle TV1", 9, true)
+	existing.Set("active", false)
+	existing.Set("category", "sports")
+
+	record, retire, _ := mergeChannel(collection, existing, []*models.Record{}, APIChannel{ID: 1, Name: "Yle TV1 HD", ShowOrder: 2, LogoURL: "https://example.com/tv1.png"})
+
+	if record != existing || len(retire) != 0 {
+		t.Fatalf("want the existing record updated in place, nothing retired")
+	}
+	if record.GetString("name") != "Yle TV1 HD" || record.GetString("logo_url") != "https://example.com/tv1.png" {
+		t.Errorf("name/logo_url not refreshed: %q %q", record.GetString("name"), record.GetString("logo_url"))
+	}
+	if record.GetInt("show_order") != 9 || record.GetBool("active") || record.GetString("category") != "sports" {
+		t.Errorf("curated fields changed: show_order=%d active=%v category=%q",
+			record.GetInt("show_order"), record.GetBool("active"), record.GetString("category"))
+	}
+}
+
+func TestMergeChannelUpdatesOrderWithoutOverride(t *testing.T) {
+	collection := &models.Collection{Name: "channels"}
+	existing := channelRecord(collection, "1", "Yle TV1", 9, false)
+
+	record, retire, _ := mergeChannel(collection, existing, []*models.Record{existing}, APIChannel{ID: 1, Name: "Yle TV1", ShowOrder: 2})
+
+	if record.GetInt("show_order") != 2 {
+		t.Errorf("show_order = %d, want 2 from the API", record.GetInt("show_order"))
+	}
+	if len(retire) != 0 {
+		t.Errorf("retire = %d, the record itself must not be retired", len(retire))
+	}
+}
+
+// fakeSource serves a fixed channel list and no programs
+type fakeSource struct {
+	channels []APIChannel
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) Channels(ctx context.Context) ([]APIChannel, error) {
+	return s.channels, nil
+}
+
+func (s *fakeSource) Programs(ctx context.Context, channelID, date string) ([]TVProgram, error) {
+	return nil, nil
+}
+
+func (s *fakeSource) Delay() time.Duration { return 0 }
+
+// activeChannelIDs returns the IDs of the active channel rows
+func activeChannelIDs(t *testing.T, collector *TVCollector) []string {
+	t.Helper()
+	records, err := collector.app.Dao().FindRecordsByExpr("channels", dbx.HashExp{"active": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, record := range records {
+		ids = append(ids, record.Id)
+	}
+	return ids
+}
+
+func TestUpdateChannelListKeepsOneActiveRow(t *testing.T) {
+	app := newTestApp(t)
+	source := &fakeSource{}
+	collector := NewTVCollector(app)
+	collector.Source = source
+
+	// The API renumbers the channel and later switches back to the old ID
+	for _, id := range []int{1, 2, 1, 1, 3} {
+		source.channels = []APIChannel{
+			{ID: id, Name: "Yle TV1", ShowOrder: 1},
+			{ID: 10, Name: "MTV3", ShowOrder: 2},
+		}
+		if err := collector.UpdateChannelList(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		got := activeChannelIDs(t, collector)
+		want := map[string]bool{fmt.Sprint(id): true, "10": true}
+		if len(got) != len(want) || !want[got[0]] || !want[got[1]] {
+			t.Fatalf("after the list with ID %d, active channels = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestParseProgramsResponseNoData(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"  \n",
+		"null",
+		"[]",
+		"{}",
+		" { } ",
+		`{"error": "No programs for this date"}`,
+		`{"Error": "not found", "status": 404}`,
+		`{"errors": ["channel has no data"]}`,
+		`{"message": "nothing scheduled", "status": "ok"}`,
+	} {
+		programs, err := parseProgramsResponse([]byte(body))
+		if err != nil {
+			t.Errorf("parseProgramsResponse(%q) failed: %v", body, err)
+			continue
+		}
+		if programs == nil || len(programs) != 0 {
+			t.Errorf("parseProgramsResponse(%q) = %#v, want an empty list", body, programs)
+		}
+	}
+}
+
+func TestParseProgramsResponsePrograms(t *testing.T) {
+	body := `[{"id": 1, "name": "Uutiset", "start": 1700000000, "stop": 1700001800, "channel": 1},
+		{"id": 2, "name": "Sää", "start": 1700001800, "stop": 1700002100, "channel": 1}]`
+
+	programs, err := parseProgramsResponse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(programs) != 2 || programs[0].Name != "Uutiset" || programs[1].Start != 1700001800 {
+		t.Errorf("programs = %+v", programs)
+	}
+}
+
+func TestParseProgramsResponseMalformed(t *testing.T) {
+	for _, body := range []string{
+		`[{"id": 1,`,
+		`{"error": `,
+		`{"programs": []}`,
+		`"just a string"`,
+		`<html>502 Bad Gateway</html>`,
+	} {
+		if programs, err := parseProgramsResponse([]byte(body)); err == nil {
+			t.Errorf("parseProgramsResponse(%q) = %+v, want an error", body, programs)
+		}
+	}
+}
+
+// benchmarkPrograms returns n programs of one channel/day, with IDs starting
+// at first so every batch creates new records
+func benchmarkPrograms(first, n int) []TVProgram {
+	programs := make([]TVProgram, n)
+	start := int64(1700000000)
+	for i := range programs {
+		programs[i] = TVProgram{
+			ID:          first + i,
+			Name:        fmt.Sprintf("Program %d", first+i),
+			Description: "A program stored by the benchmark",
+			Start:       start + int64(i)*1800,
+			Stop:        start + int64(i+1)*1800,
+			Channel:     1,
+		}
+	}
+	return programs
+}
+
+const benchmarkBatchSize = 100
+
+// BenchmarkStoreProgramsBatched stores a channel/day in one transaction
+func BenchmarkStoreProgramsBatched(b *testing.B) {
+	app := newTestApp(b)
+	insertRows(b, app, "channels", dbx.Params{"id": "1", "name": "Yle TV1", "active": true})
+	collector := NewTVCollector(app)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		programs := benchmarkPrograms(i*benchmarkBatchSize, benchmarkBatchSize)
+		if _, err := collector.storePrograms(programs, "1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStoreProgramsPerRecord stores the same batches one write at a
+// time, as storeProgram did before batching
+func BenchmarkStoreProgramsPerRecord(b *testing.B) {
+	app := newTestApp(b)
+	insertRows(b, app, "channels", dbx.Params{"id": "1", "name": "Yle TV1", "active": true})
+	collection, err := app.Dao().FindCollectionByNameOrId("programs")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, prog := range benchmarkPrograms(i*benchmarkBatchSize, benchmarkBatchSize) {
+			if _, err := storeProgram(app.Dao(), collection, prog, "1"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
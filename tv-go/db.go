@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/pocketbase/dbx"
+)
+
+// SQLite settings the data DB needs so API reads don't hit "database is
+// locked" while a fetch writes. PocketBase opens every pool connection with
+// journal_mode=WAL, busy_timeout=10000 and synchronous=NORMAL already;
+// checkDatabasePragmas makes sure that is what we actually got.
+const (
+	WantJournalMode  = "wal"
+	MinBusyTimeoutMs = 5000
+	WantSynchronous  = 1 // NORMAL
+)
+
+// dbPragmas are the effective settings of one DB connection
+type dbPragmas struct {
+	JournalMode string
+	BusyTimeout int
+	Synchronous int
+}
+
+func readPragmas(db *dbx.DB) (dbPragmas, error) {
+	var p dbPragmas
+	if err := db.NewQuery("PRAGMA journal_mode").Row(&p.JournalMode); err != nil {
+		return p, fmt.Errorf("reading journal_mode: %w", err)
+	}
+	if err := db.NewQuery("PRAGMA busy_timeout").Row(&p.BusyTimeout); err != nil {
+		return p, fmt.Errorf("reading busy_timeout: %w", err)
+	}
+	if err := db.NewQuery("PRAGMA synchronous").Row(&p.Synchronous); err != nil {
+		return p, fmt.Errorf("reading synchronous: %w", err)
+	}
+	return p, nil
+}
+
+// checkDatabasePragmas switches the DB file to WAL if it isn't (the journal
+// mode is stored in the file, so once is enough) and warns when the
+// per-connection settings are weaker than expected
+func checkDatabasePragmas(db *dbx.DB) error {
+	p, err := readPragmas(db)
+	if err != nil {
+		return err
+	}
+
+	if p.JournalMode != WantJournalMode {
+		if err := db.NewQuery("PRAGMA journal_mode = WAL").Row(&p.JournalMode); err != nil {
+			return fmt.Errorf("enabling WAL: %w", err)
+		}
+	}
+
+	if p.JournalMode != WantJournalMode {
+		log.Printf("⚠️  SQLite journal_mode is %s, expected WAL", p.JournalMode)
+	}
+	if p.BusyTimeout < MinBusyTimeoutMs {
+		log.Printf("⚠️  SQLite busy_timeout is %dms, expected at least %dms", p.BusyTimeout, MinBusyTimeoutMs)
+	}
+	if p.Synchronous != WantSynchronous {
+		log.Printf("⚠️  SQLite synchronous is %d, expected %d (NORMAL)", p.Synchronous, WantSynchronous)
+	}
+
+	log.Printf("🗄️  SQLite: journal_mode=%s busy_timeout=%dms synchronous=%d", p.JournalMode, p.BusyTimeout, p.Synchronous)
+	return nil
+}
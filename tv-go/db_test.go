@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+)
+
+func TestDatabasePragmasApplied(t *testing.T) {
+	app := newTestApp(t)
+
+	if err := checkDatabasePragmas(app.DB()); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := readPragmas(app.DB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.JournalMode != WantJournalMode {
+		t.Errorf("journal_mode = %s, want %s", p.JournalMode, WantJournalMode)
+	}
+	if p.BusyTimeout < MinBusyTimeoutMs {
+		t.Errorf("busy_timeout = %dms, want at least %dms", p.BusyTimeout, MinBusyTimeoutMs)
+	}
+	if p.Synchronous != WantSynchronous {
+		t.Errorf("synchronous = %d, want %d", p.Synchronous, WantSynchronous)
+	}
+}
+
+func TestCheckDatabasePragmasEnablesWAL(t *testing.T) {
+	app := newTestApp(t)
+
+	// A separate database file switched back to the rollback journal, on a
+	// single connection so the switch isn't blocked by the pool
+	db, err := dbx.Open(app.DB().DriverName(), filepath.Join(t.TempDir(), "plain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+
+	var mode string
+	if err := db.NewQuery("PRAGMA journal_mode = DELETE").Row(&mode); err != nil || mode != "delete" {
+		t.Fatalf("switching to the rollback journal: %q, %v", mode, err)
+	}
+
+	if err := checkDatabasePragmas(db); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := readPragmas(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.JournalMode != WantJournalMode {
+		t.Errorf("journal_mode = %s after the check, want %s", after.JournalMode, WantJournalMode)
+	}
+}
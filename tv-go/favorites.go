@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Upcoming airings returned by /api/tv/favorites/upcoming
+const (
+	DefaultFavoritesUpcomingLimit = 50
+	MaxFavoritesUpcomingLimit     = 200
+)
+
+// listFavorites returns the user's favorites, newest first, with their
+// series attached
+func listFavorites(app *pocketbase.PocketBase, userID string) ([]map[string]any, error) {
+	records, err := app.Dao().FindRecordsByFilter(
+		"favorites",
+		"user = {:user}",
+		"-created",
+		0,
+		0,
+		dbx.Params{"user": userID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		seriesIDs = append(seriesIDs, record.GetString("series"))
+	}
+	series := make(map[string]*models.Record, len(seriesIDs))
+	if len(seriesIDs) > 0 {
+		seriesRecords, err := app.Dao().FindRecordsByIds("series", seriesIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range seriesRecords {
+			series[record.Id] = record
+		}
+	}
+
+	favorites := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		favorites = append(favorites, favoriteResponse(record, series[record.GetString("series")]))
+	}
+	return favorites, nil
+}
+
+// findFavorite returns the user's favorite of a series, nil when there is none
+func findFavorite(app *pocketbase.PocketBase, userID, seriesID string) *models.Record {
+	record, err := app.Dao().FindFirstRecordByFilter(
+		"favorites",
+		"user = {:user} && series = {:series}",
+		dbx.Params{"user": userID, "series": seriesID},
+	)
+	if err != nil {
+		return nil
+	}
+	return record
+}
+
+// addFavorite favorites series for the user. It reports false when the
+// series already was a favorite, returning the existing record.
+func addFavorite(app *pocketbase.PocketBase, userID string, series *models.Record) (*models.Record, bool, error) {
+	if existing := findFavorite(app, userID, series.Id); existing != nil {
+		return existing, false, nil
+	}
+
+	collection, err := app.Dao().FindCollectionByNameOrId("favorites")
+	if err != nil {
+		return nil, false, err
+	}
+	record := models.NewRecord(collection)
+	record.Set("user", userID)
+	record.Set("series", series.Id)
+	if err := app.Dao().SaveRecord(record); err != nil {
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+// upcomingFavorites returns the next airings of the user's favorite series
+// on active channels, soonest first, with their channels expanded
+func upcomingFavorites(app *pocketbase.PocketBase, userID string, limit int) ([]map[string]any, error) {
+	records := []*models.Record{}
+	err := app.Dao().RecordQuery("programs").
+		Select("p.*").
+		From("programs p").
+		InnerJoin("favorites f", dbx.NewExp("f.series = p.series")).
+		InnerJoin("channels c", dbx.NewExp("c.id = p.channel")).
+		Where(dbx.HashExp{"f.user": userID, "c.active": true}).
+		AndWhere(dbx.NewExp("p.start_time > {:now}", dbx.Params{"now": types.NowDateTime().String()})).
+		OrderBy("p.start_time ASC", "p.id ASC").
+		Limit(int64(limit)).
+		All(&records)
+	if err != nil {
+		return nil, err
+	}
+	return expandChannels(app, records)
+}
+
+func favoriteResponse(favorite, series *models.Record) map[string]any {
+	response := map[string]any{
+		"id":      favorite.Id,
+		"series":  favorite.GetString("series"),
+		"created": favorite.Created.String(),
+	}
+	if series != nil {
+		response["expand"] = map[string]any{
+			"series": series.PublicExport(),
+		}
+	}
+	return response
+}
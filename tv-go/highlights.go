@@ -0,0 +1,123 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Highlights defaults, the rating threshold is overridable with HIGHLIGHTS_MIN_RATING
+const (
+	DefaultHighlightsMinRating = 4
+	DefaultHighlightsPerDay    = 10
+	MaxHighlightsPerDay        = 50
+	MaxHighlightsRangeDays     = 31
+
+	// New series are first seen up to a fetch horizon before they air
+	PremiereLookback = 8 * 24 * time.Hour
+)
+
+// highlightDay is one day of /api/tv/highlights, programs sorted by rating
+type highlightDay struct {
+	Date  string           `json:"date"`
+	Items []map[string]any `json:"items"`
+}
+
+// findHighlights returns programs starting in [from, to) that are rated at
+// least minRating or are the first airing of a newly seen series, grouped by
+// local day and sorted by rating, at most perDay per day
+func findHighlights(app *pocketbase.PocketBase, from, to time.Time, minRating, perDay int) ([]highlightDay, error) {
+	fromDT, err := types.ParseDateTime(from)
+	if err != nil {
+		return nil, err
+	}
+	toDT, err := types.ParseDateTime(to)
+	if err != nil {
+		return nil, err
+	}
+	premiereDT, err := types.ParseDateTime(from.Add(-PremiereLookback))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID       string `db:"id"`
+		Premiere bool   `db:"premiere"`
+	}
+	err = app.Dao().DB().NewQuery(`
+		SELECT id, premiere FROM (
+			SELECT p.id AS id, p.rating AS rating,
+				(s.id IS NOT NULL AND s.first_seen >= {:premiereSince} AND p.start_time = (
+					SELECT MIN(p2.start_time) FROM programs p2 WHERE p2.series = p.series
+				)) AS premiere
+			FROM programs p
+			LEFT JOIN series s ON s.id = p.series
+			WHERE p.start_time >= {:from} AND p.start_time < {:to}
+		)
+		WHERE rating >= {:minRating} OR premiere
+	`).Bind(dbx.Params{
+		"from":          fromDT.String(),
+		"to":            toDT.String(),
+		"premiereSince": premiereDT.String(),
+		"minRating":     minRating,
+	}).All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	premieres := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+		premieres[row.ID] = row.Premiere
+	}
+	if len(ids) == 0 {
+		return []highlightDay{}, nil
+	}
+
+	records, err := app.Dao().FindRecordsByIds("programs", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Best rated first
+	sort.SliceStable(records, func(i, j int) bool {
+		ri, rj := records[i].GetInt("rating"), records[j].GetInt("rating")
+		if ri != rj {
+			return ri > rj
+		}
+		return records[i].GetDateTime("start_time").Time().Before(records[j].GetDateTime("start_time").Time())
+	})
+
+	expanded, err := expandChannels(app, records)
+	if err != nil {
+		return nil, err
+	}
+
+	days := []highlightDay{}
+	byDate := map[string]int{}
+	for i, record := range records {
+		date := record.GetDateTime("start_time").Time().In(time.Local).Format("2006-01-02")
+		idx, ok := byDate[date]
+		if !ok {
+			idx = len(days)
+			byDate[date] = idx
+			days = append(days, highlightDay{Date: date, Items: []map[string]any{}})
+		}
+		if len(days[idx].Items) >= perDay {
+			continue
+		}
+
+		expanded[i]["premiere"] = premieres[record.Id]
+		days[idx].Items = append(days[idx].Items, expanded[i])
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date < days[j].Date
+	})
+
+	return days, nil
+}
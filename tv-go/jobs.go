@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ShutdownTimeout is how long shutdown waits for in-flight jobs
+const ShutdownTimeout = 30 * time.Second
+
+// JobTracker keeps track of background jobs so shutdown can cancel them and
+// wait for them to finish instead of exiting mid-write
+type JobTracker struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running int
+	closed  bool
+
+	// cancels holds cancel funcs of jobs started with GoCancelable, by name
+	cancels map[string]context.CancelFunc
+}
+
+func NewJobTracker() *JobTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobTracker{
+		ctx:     ctx,
+		cancel:  cancel,
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// Run executes fn synchronously as a tracked job. The context passed to fn is
+// canceled when shutdown starts.
+func (t *JobTracker) Run(name string, fn func(ctx context.Context)) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		log.Printf("⚠️  Not starting job %s, shutting down", name)
+		return
+	}
+	t.wg.Add(1)
+	t.running++
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.running--
+		t.mu.Unlock()
+		t.wg.Done()
+	}()
+
+	fn(t.ctx)
+}
+
+// Go executes fn as a tracked job in a new goroutine
+func (t *JobTracker) Go(name string, fn func(ctx context.Context)) {
+	go t.Run(name, fn)
+}
+
+// GoCancelable is like Go but derives a cancelable context for the job and
+// keeps its cancel func under name until the job returns, so it can be
+// aborted with Cancel. Only one job per name runs at a time: it returns
+// false, starting nothing, while one is still running or after shutdown.
+func (t *JobTracker) GoCancelable(name string, fn func(ctx context.Context)) bool {
+	t.mu.Lock()
+	if _, running := t.cancels[name]; running || t.closed {
+		t.mu.Unlock()
+		return false
+	}
+	// Registered before the goroutine starts, so a second call can't slip in
+	jobCtx, cancel := context.WithCancel(t.ctx)
+	t.cancels[name] = cancel
+	t.mu.Unlock()
+
+	go func() {
+		defer func() {
+			cancel()
+			t.mu.Lock()
+			delete(t.cancels, name)
+			t.mu.Unlock()
+		}()
+
+		t.Run(name, func(context.Context) {
+			fn(jobCtx)
+		})
+	}()
+	return true
+}
+
+// Cancel aborts the running cancelable job with the given name, reporting
+// whether one was running
+func (t *JobTracker) Cancel(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cancel, ok := t.cancels[name]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// Running reports whether a cancelable job with the given name is in progress
+func (t *JobTracker) Running(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.cancels[name]
+	return ok
+}
+
+// Shutdown stops accepting jobs, cancels running ones and waits up to timeout
+// for them to return. It reports how many jobs were awaited and whether they
+// all finished in time.
+func (t *JobTracker) Shutdown(timeout time.Duration) (int, bool) {
+	t.mu.Lock()
+	t.closed = true
+	awaited := t.running
+	t.mu.Unlock()
+
+	t.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return awaited, true
+	case <-time.After(timeout):
+		return awaited, false
+	}
+}
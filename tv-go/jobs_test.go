@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGoCancelableOneRunPerName(t *testing.T) {
+	jobs := NewJobTracker()
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	if !jobs.GoCancelable("fetch", func(ctx context.Context) {
+		defer close(done)
+		<-release
+	}) {
+		t.Fatal("first run was refused")
+	}
+	if jobs.GoCancelable("fetch", func(ctx context.Context) {}) {
+		t.Fatal("second run started while the first was running")
+	}
+	if !jobs.Running("fetch") {
+		t.Fatal("Running = false while the job runs")
+	}
+
+	close(release)
+	<-done
+	waitUntil(t, func() bool { return !jobs.Running("fetch") })
+	if !jobs.GoCancelable("fetch", func(ctx context.Context) {}) {
+		t.Fatal("run refused after the previous one returned")
+	}
+}
+
+func TestCancelStopsTheRunningJob(t *testing.T) {
+	jobs := NewJobTracker()
+	canceled := make(chan struct{})
+
+	jobs.GoCancelable("fetch", func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	})
+	if !jobs.Cancel("fetch") {
+		t.Fatal("Cancel = false for a running job")
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("job context not canceled")
+	}
+	waitUntil(t, func() bool { return !jobs.Running("fetch") })
+	if jobs.Cancel("fetch") {
+		t.Fatal("Cancel = true with no job running")
+	}
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// DefaultLogoCacheTTL is how long a cached logo is served before it is
+// fetched again, the weekly channel update refreshes them anyway
+const DefaultLogoCacheTTL = 7 * 24 * time.Hour
+
+// LogoPlaceholderMaxAge is the browser cache lifetime of the placeholder, kept
+// short so a recovered upstream shows up soon
+const LogoPlaceholderMaxAge = 5 * time.Minute
+
+// MaxLogoBytes caps a downloaded logo
+const MaxLogoBytes = 2 * 1024 * 1024
+
+// errNoLogo means the channel has no logo_url
+var errNoLogo = errors.New("channel has no logo")
+
+// logoCache is shared by the logo route and the channel update, which
+// refreshes every active channel's logo
+var logoCache = NewLogoCache(time.Duration(envInt("LOGO_CACHE_TTL", int(DefaultLogoCacheTTL.Seconds()))) * time.Second)
+
+// LogoCache keeps channel logos on disk under logoCacheDir, one image and one
+// JSON metadata file per channel
+type LogoCache struct {
+	mu     sync.Mutex             // guards locks
+	locks  map[string]*sync.Mutex // per channel, serializes its fetch and cache files
+	ttl    time.Duration
+	client *http.Client
+}
+
+// logoMeta is the metadata file stored next to a cached logo
+type logoMeta struct {
+	SourceURL    string    `json:"source_url"`
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag"` // ours, over the image bytes
+	UpstreamETag string    `json:"upstream_etag,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+type cachedLogo struct {
+	logoMeta
+	Data []byte
+}
+
+func NewLogoCache(ttl time.Duration) *LogoCache {
+	return &LogoCache{
+		locks:  make(map[string]*sync.Mutex),
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// logoCacheDir is LOGO_CACHE_DIR, or logos/ in the PocketBase data dir
+func logoCacheDir(app *pocketbase.PocketBase) string {
+	if dir := os.Getenv("LOGO_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(app.DataDir(), "logos")
+}
+
+// channelLock returns the lock of one channel's cache entry. Requests for
+// other channels don't wait on its upstream fetch.
+func (l *LogoCache) channelLock(channelID string) *sync.Mutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[channelID]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[channelID] = lock
+	}
+	return lock
+}
+
+// Get returns the channel's logo, fetching it when it isn't cached, the
+// cached copy has expired or logo_url changed. When upstream fails an
+// expired copy of the same URL is still served. Concurrent requests for the
+// same channel wait for one fetch and then share its result.
+func (l *LogoCache) Get(ctx context.Context, app *pocketbase.PocketBase, channel *models.Record) (*cachedLogo, error) {
+	lock := l.channelLock(channel.Id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return l.get(ctx, app, channel, false)
+}
+
+// Refresh re-fetches the logos of channels, skipping those without one.
+// Failures are logged and leave the previous copy in place.
+func (l *LogoCache) Refresh(ctx context.Context, app *pocketbase.PocketBase, channels []*models.Record) {
+	refreshed, total := 0, 0
+	for _, channel := range channels {
+		if ctx.Err() != nil {
+			break
+		}
+		if channel.GetString("logo_url") == "" {
+			continue
+		}
+		total++
+		if _, err := l.refresh(ctx, app, channel); err != nil {
+			log.Printf("  ⚠️  Failed to refresh logo of %s: %v", channel.GetString("name"), err)
+			continue
+		}
+		refreshed++
+	}
+	log.Printf("🖼️  Refreshed %d/%d channel logos", refreshed, total)
+}
+
+// refresh force-fetches one channel's logo, holding only its lock
+func (l *LogoCache) refresh(ctx context.Context, app *pocketbase.PocketBase, channel *models.Record) (*cachedLogo, error) {
+	lock := l.channelLock(channel.Id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return l.get(ctx, app, channel, true)
+}
+
+// get serves or fetches a logo, the caller holds the channel's lock
+func (l *LogoCache) get(ctx context.Context, app *pocketbase.PocketBase, channel *models.Record, force bool) (*cachedLogo, error) {
+	url := channel.GetString("logo_url")
+	if url == "" {
+		return nil, errNoLogo
+	}
+
+	dir := logoCacheDir(app)
+	cached, _ := loadLogo(dir, channel.Id)
+	if cached != nil && cached.SourceURL != url {
+		cached = nil
+	}
+	if cached != nil && !force && time.Since(cached.FetchedAt) < l.ttl {
+		return cached, nil
+	}
+
+	logo, err := l.fetch(ctx, url, cached)
+	if err != nil {
+		if cached != nil {
+			log.Printf("⚠️  Serving stale logo of %s: %v", channel.GetString("name"), err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	if err := saveLogo(dir, channel.Id, logo); err != nil {
+		log.Printf("⚠️  Failed to cache logo of %s: %v", channel.GetString("name"), err)
+	}
+	return logo, nil
+}
+
+// fetch downloads url, revalidating against the upstream ETag of previous
+func (l *LogoCache) fetch(ctx context.Context, url string, previous *cachedLogo) (*cachedLogo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.UpstreamETag != "" {
+		req.Header.Set("If-None-Match", previous.UpstreamETag)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && previous != nil {
+		logo := *previous
+		logo.FetchedAt = time.Now()
+		return &logo, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxLogoBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxLogoBytes {
+		return nil, fmt.Errorf("logo larger than %d bytes", MaxLogoBytes)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("not an image: %s", contentType)
+	}
+
+	sum := sha256.Sum256(data)
+	return &cachedLogo{
+		logoMeta: logoMeta{
+			SourceURL:    url,
+			ContentType:  contentType,
+			ETag:         `"` + hex.EncodeToString(sum[:8]) + `"`,
+			UpstreamETag: resp.Header.Get("ETag"),
+			FetchedAt:    time.Now(),
+		},
+		Data: data,
+	}, nil
+}
+
+func loadLogo(dir, channelID string) (*cachedLogo, error) {
+	metaJSON, err := os.ReadFile(filepath.Join(dir, channelID+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var logo cachedLogo
+	if err := json.Unmarshal(metaJSON, &logo.logoMeta); err != nil {
+		return nil, err
+	}
+	if logo.Data, err = os.ReadFile(filepath.Join(dir, channelID+".img")); err != nil {
+		return nil, err
+	}
+	return &logo, nil
+}
+
+// saveLogo writes the image before the metadata, so metadata never points
+// at a missing image
+func saveLogo(dir, channelID string, logo *cachedLogo) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, channelID+".img"), logo.Data, 0644); err != nil {
+		return err
+	}
+	metaJSON, err := json.Marshal(logo.logoMeta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, channelID+".json"), metaJSON, 0644)
+}
+
+// etagMatches reports whether an If-None-Match header covers etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderLogo is an SVG with the channel's initials, served when there
+// is no logo to show
+func placeholderLogo(channelName string) []byte {
+	initials := ""
+	for _, word := range strings.Fields(channelName) {
+		initials += string([]rune(word)[:1])
+		if len([]rune(initials)) == 2 {
+			break
+		}
+	}
+	if initials == "" {
+		initials = "TV"
+	}
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="96" height="96" viewBox="0 0 96 96">`+
+		`<rect width="96" height="96" rx="12" fill="#3c3c3c"/>`+
+		`<text x="48" y="60" font-family="sans-serif" font-size="36" fill="#ffffff" text-anchor="middle">%s</text>`+
+		`</svg>`, html.EscapeString(strings.ToUpper(initials))))
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
@@ -11,7 +13,19 @@ import (
 )
 
 func main() {
-	app := pocketbase.New()
+	// PB_DATA_DIR moves pb_data, the --dir flag still takes precedence
+	app := pocketbase.NewWithConfig(pocketbase.Config{
+		DefaultDataDir: os.Getenv("PB_DATA_DIR"),
+	})
+	jobs := NewJobTracker()
+
+	// Check the SQLite settings once the data DB is open
+	app.OnAfterBootstrap().Add(func(e *core.BootstrapEvent) error {
+		if err := checkDatabasePragmas(app.DB()); err != nil {
+			log.Printf("⚠️  Could not check SQLite settings: %v", err)
+		}
+		return nil
+	})
 
 	// Enable auto creation of migration files
 	migratecmd.MustRegister(app, app.RootCmd, migratecmd.Config{
@@ -34,34 +48,54 @@ func main() {
 
 		// Job 1: Fetch TV program data daily at 01:00
 		scheduler.MustAdd("fetch_programs", "0 1 * * *", func() {
-			log.Println("🔄 Starting nightly program data fetch...")
-			collector := NewTVCollector(app)
-			if err := collector.FetchAllPrograms(7); err != nil {
-				log.Printf("❌ Program fetch failed: %v", err)
-			} else {
-				log.Println("✅ Program fetch completed successfully")
-			}
+			jobs.Run("fetch_programs", func(ctx context.Context) {
+				log.Println("🔄 Starting nightly program data fetch...")
+				collector := NewTVCollector(app)
+				summary, err := collector.FetchAllPrograms(ctx, DefaultFetchOptions(7))
+				if err != nil {
+					log.Printf("❌ Program fetch failed: %v", err)
+				} else {
+					log.Println("✅ Program fetch completed successfully")
+				}
+				app.Logger().Info("Nightly fetch summary", summary.LogAttrs()...)
+			})
 		})
 
 		// Job 2: Clean up old programs daily at 02:00
 		scheduler.MustAdd("cleanup_old_data", "0 2 * * *", func() {
-			log.Println("🧹 Starting data cleanup...")
-			if err := cleanupOldData(app, 30); err != nil {
-				log.Printf("❌ Cleanup failed: %v", err)
-			} else {
-				log.Println("✅ Cleanup completed successfully")
-			}
+			jobs.Run("cleanup_old_data", func(ctx context.Context) {
+				log.Println("🧹 Starting data cleanup...")
+				if err := cleanupOldData(app, RetentionFromEnv(DefaultRetentionDays)); err != nil {
+					log.Printf("❌ Cleanup failed: %v", err)
+				} else {
+					log.Println("✅ Cleanup completed successfully")
+				}
+			})
 		})
 
 		// Job 3: Update channel list weekly (Sunday at 03:00)
 		scheduler.MustAdd("update_channels", "0 3 * * 0", func() {
-			log.Println("📡 Updating channel list...")
-			collector := NewTVCollector(app)
-			if err := collector.UpdateChannelList(); err != nil {
-				log.Printf("❌ Channel update failed: %v", err)
-			} else {
-				log.Println("✅ Channel list updated successfully")
-			}
+			jobs.Run("update_channels", func(ctx context.Context) {
+				log.Println("📡 Updating channel list...")
+				collector := NewTVCollector(app)
+				if err := collector.UpdateChannelList(ctx); err != nil {
+					log.Printf("❌ Channel update failed: %v", err)
+				} else {
+					log.Println("✅ Channel list updated successfully")
+				}
+			})
+		})
+
+		// Job 4: Schedule reminders for favorite series hourly
+		scheduler.MustAdd("schedule_reminders", "0 * * * *", func() {
+			jobs.Run("schedule_reminders", func(ctx context.Context) {
+				scheduled, err := scheduleReminders(ctx, app)
+				if err != nil {
+					log.Printf("❌ Reminder scheduling failed: %v", err)
+				} else if scheduled > 0 {
+					log.Printf("🔔 Scheduled %d reminders", scheduled)
+				}
+			})
 		})
 
 		scheduler.Start()
@@ -70,13 +104,25 @@ func main() {
 		log.Println("   - fetch_programs: Daily at 01:00")
 		log.Println("   - cleanup_old_data: Daily at 02:00")
 		log.Println("   - update_channels: Weekly on Sunday at 03:00")
+		log.Println("   - schedule_reminders: Hourly")
 
 		return nil
 	})
 
 	// Add custom API endpoints
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		return setupCustomRoutes(app, e)
+		return setupCustomRoutes(app, e, jobs)
+	})
+
+	// Wait for in-flight jobs before exiting so fetches don't stop mid-write
+	app.OnTerminate().Add(func(e *core.TerminateEvent) error {
+		awaited, finished := jobs.Shutdown(ShutdownTimeout)
+		if finished {
+			log.Printf("🛑 Shutdown: %d background job(s) finished", awaited)
+		} else {
+			log.Printf("⚠️  Shutdown: timed out waiting for %d background job(s)", awaited)
+		}
+		return nil
 	})
 
 	if err := app.Start(); err != nil {
@@ -87,3 +133,13 @@ func main() {
 func isDevMode() bool {
 	return os.Getenv("ENV") == "development"
 }
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// MaxOverlapReport caps the overlaps listed in an OverlapReport, the counts
+// always cover all of them
+const MaxOverlapReport = 500
+
+// Overlap actions
+const (
+	OverlapMerged  = "merged"  // same program stored twice, one copy removed
+	OverlapFlagged = "flagged" // different programs, left for a human to check
+)
+
+// overlapRow is the part of a program the overlap scan needs
+type overlapRow struct {
+	ID        string `db:"id" json:"id"`
+	Channel   string `db:"channel" json:"-"`
+	Name      string `db:"name" json:"name"`
+	StartTime string `db:"start_time" json:"start_time"`
+	EndTime   string `db:"end_time" json:"end_time"`
+	Updated   string `db:"updated" json:"-"`
+}
+
+// programOverlap is one pair of overlapping programs on a channel
+type programOverlap struct {
+	Channel string     `json:"channel"`
+	First   overlapRow `json:"first"`
+	Second  overlapRow `json:"second"`
+	Action  string     `json:"action"`
+	Removed string     `json:"removed,omitempty"` // id of the dropped duplicate
+}
+
+// OverlapReport is the outcome of repairOverlaps
+type OverlapReport struct {
+	DryRun          bool             `json:"dry_run"`
+	ChannelsScanned int              `json:"channels_scanned"`
+	ProgramsScanned int              `json:"programs_scanned"`
+	Merged          int              `json:"merged"`
+	Flagged         int              `json:"flagged"`
+	Overlaps        []programOverlap `json:"overlaps"`
+	Truncated       bool             `json:"truncated"` // more overlaps than MaxOverlapReport
+}
+
+// repairOverlaps scans each channel for programs whose time ranges overlap.
+// Overlapping programs with the same name are duplicates: the most recently
+// updated copy is kept and the other removed, unless dryRun is set. Other
+// overlaps are only reported.
+func repairOverlaps(app *pocketbase.PocketBase, dryRun bool) (OverlapReport, error) {
+	report := OverlapReport{DryRun: dryRun, Overlaps: []programOverlap{}}
+
+	var rows []overlapRow
+	err := app.Dao().DB().
+		Select("id", "channel", "name", "start_time", "end_time", "updated").
+		From("programs").
+		OrderBy("channel", "start_time", "id").
+		All(&rows)
+	if err != nil {
+		return report, err
+	}
+	report.ProgramsScanned = len(rows)
+
+	var remove []interface{}
+	var prev *overlapRow // program reaching furthest into the channel's schedule so far
+	for i := range rows {
+		row := &rows[i]
+		if prev == nil || row.Channel != prev.Channel {
+			report.ChannelsScanned++
+			prev = row
+			continue
+		}
+
+		// Stored datetimes share one format, so they compare as strings
+		if row.StartTime >= prev.EndTime {
+			prev = row
+			continue
+		}
+
+		overlap := programOverlap{Channel: row.Channel, First: *prev, Second: *row, Action: OverlapFlagged}
+		if sameProgramName(prev.Name, row.Name) {
+			overlap.Action = OverlapMerged
+			keep, drop := prev, row
+			if row.Updated > prev.Updated {
+				keep, drop = row, prev
+			}
+			overlap.Removed = drop.ID
+			remove = append(remove, drop.ID)
+			report.Merged++
+			prev = keep
+		} else {
+			report.Flagged++
+			if row.EndTime > prev.EndTime {
+				prev = row
+			}
+		}
+
+		if len(report.Overlaps) < MaxOverlapReport {
+			report.Overlaps = append(report.Overlaps, overlap)
+		} else {
+			report.Truncated = true
+		}
+	}
+
+	if dryRun || len(remove) == 0 {
+		return report, nil
+	}
+
+	err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		if _, err := txDao.DB().Delete("programs", dbx.In("id", remove...)).Execute(); err != nil {
+			return err
+		}
+		return deleteOrphanReminders(txDao.DB())
+	})
+	return report, err
+}
+
+func sameProgramName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
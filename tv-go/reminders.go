@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Reminder settings. The lead time is REMINDER_LEAD_MINUTES, overridden per
+// user by users.reminder_lead_minutes.
+const (
+	DefaultReminderLead = time.Hour
+	MaxReminderLead     = 24 * time.Hour
+
+	// ReminderJobInterval is how often the reminders job runs, each run
+	// looks this far past the lead time so no program falls between runs
+	ReminderJobInterval = time.Hour
+
+	ReminderWebhookTimeout = 10 * time.Second
+)
+
+// reminderLead is REMINDER_LEAD_MINUTES, or DefaultReminderLead when unset
+// or out of range
+func reminderLead() time.Duration {
+	lead := time.Duration(envInt("REMINDER_LEAD_MINUTES", int(DefaultReminderLead/time.Minute))) * time.Minute
+	if lead <= 0 || lead > MaxReminderLead {
+		return DefaultReminderLead
+	}
+	return lead
+}
+
+// reminderNotice is one new reminder as sent to REMINDER_WEBHOOK_URL
+type reminderNotice struct {
+	ID        string `json:"id"`
+	User      string `json:"user"`
+	Program   string `json:"program"`
+	Name      string `json:"name"`
+	Channel   string `json:"channel"`
+	StartTime string `json:"start_time"`
+	RemindAt  string `json:"remind_at"`
+}
+
+// scheduleReminders records a reminder for every upcoming program of a
+// favorite series that starts within its user's lead time, plus one job
+// interval so the next run isn't too late for it. Programs that already
+// have a reminder for the user, dismissed or not, are skipped. New reminders are posted to
+// REMINDER_WEBHOOK_URL when it is set. It returns how many were recorded.
+func scheduleReminders(ctx context.Context, app *pocketbase.PocketBase) (int, error) {
+	now := time.Now()
+	defaultLead := reminderLead()
+
+	nowDT, err := types.ParseDateTime(now)
+	if err != nil {
+		return 0, err
+	}
+	horizonDT, err := types.ParseDateTime(now.Add(MaxReminderLead + ReminderJobInterval))
+	if err != nil {
+		return 0, err
+	}
+
+	var rows []struct {
+		User        string         `db:"user"`
+		Program     string         `db:"program"`
+		Name        string         `db:"name"`
+		Channel     string         `db:"channel"`
+		Start       types.DateTime `db:"start_time"`
+		LeadMinutes int            `db:"lead_minutes"`
+	}
+	err = app.Dao().DB().NewQuery(`
+		SELECT f.user AS user, p.id AS program, p.name AS name, c.name AS channel,
+			p.start_time AS start_time, CAST(COALESCE(u.reminder_lead_minutes, 0) AS INTEGER) AS lead_minutes
+		FROM favorites f
+		INNER JOIN programs p ON p.series = f.series
+		INNER JOIN channels c ON c.id = p.channel
+		INNER JOIN users u ON u.id = f.user
+		WHERE c.active = TRUE AND p.start_time > {:now} AND p.start_time <= {:horizon}
+			AND NOT EXISTS (SELECT 1 FROM reminders r WHERE r.user = f.user AND r.program = p.id)
+		ORDER BY p.start_time
+	`).Bind(dbx.Params{
+		"now":     nowDT.String(),
+		"horizon": horizonDT.String(),
+	}).All(&rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find favorite programs: %w", err)
+	}
+
+	collection, err := app.Dao().FindCollectionByNameOrId("reminders")
+	if err != nil {
+		return 0, err
+	}
+
+	var notices []reminderNotice
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+
+		lead := defaultLead
+		if row.LeadMinutes > 0 {
+			lead = time.Duration(row.LeadMinutes) * time.Minute
+		}
+		start := row.Start.Time()
+		if start.After(now.Add(lead + ReminderJobInterval)) {
+			continue // a later run is still in time
+		}
+		remindAt, err := types.ParseDateTime(start.Add(-lead))
+		if err != nil {
+			continue
+		}
+
+		record := models.NewRecord(collection)
+		record.Set("user", row.User)
+		record.Set("program", row.Program)
+		record.Set("remind_at", remindAt)
+		record.Set("start_time", row.Start)
+		// The unique (user, program) index also guards against a concurrent run
+		if err := app.Dao().SaveRecord(record); err != nil {
+			log.Printf("  ⚠️  Failed to record reminder of %s for %s: %v", row.Name, row.User, err)
+			continue
+		}
+
+		notices = append(notices, reminderNotice{
+			ID:        record.Id,
+			User:      row.User,
+			Program:   row.Program,
+			Name:      row.Name,
+			Channel:   row.Channel,
+			StartTime: row.Start.String(),
+			RemindAt:  remindAt.String(),
+		})
+	}
+
+	if url := os.Getenv("REMINDER_WEBHOOK_URL"); url != "" && len(notices) > 0 {
+		if err := postReminders(ctx, url, notices); err != nil {
+			log.Printf("⚠️  Reminder webhook failed: %v", err)
+		}
+	}
+	return len(notices), nil
+}
+
+// postReminders sends new reminders to the webhook as {"reminders": [...]}
+func postReminders(ctx context.Context, url string, notices []reminderNotice) error {
+	body, err := json.Marshal(map[string]any{"reminders": notices})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ReminderWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// dismissReminder marks a reminder dismissed, keeping the record so the
+// job doesn't schedule it again
+func dismissReminder(app *pocketbase.PocketBase, reminder *models.Record) error {
+	reminder.Set("dismissed", true)
+	return app.Dao().SaveRecord(reminder)
+}
+
+// deleteOrphanReminders removes reminders whose program is gone. Programs
+// deleted with plain SQL skip the relation's cascade delete, so the cleanup,
+// purge and overlap repair call this after deleting.
+func deleteOrphanReminders(db dbx.Builder) error {
+	_, err := db.NewQuery(`
+		DELETE FROM reminders
+		WHERE program NOT IN (SELECT id FROM programs)
+	`).Execute()
+	return err
+}
+
+// pendingReminders returns the user's undismissed reminders that are due for programs
+// that haven't started yet, soonest first, with the program and its
+// channel attached
+func pendingReminders(app *pocketbase.PocketBase, userID string) ([]map[string]any, error) {
+	now := types.NowDateTime().String()
+	records, err := app.Dao().FindRecordsByFilter(
+		"reminders",
+		"user = {:user} && dismissed = false && remind_at <= {:now} && start_time > {:now}",
+		"start_time",
+		0,
+		0,
+		dbx.Params{"user": userID, "now": now},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	programIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		programIDs = append(programIDs, record.GetString("program"))
+	}
+	programs := make(map[string]map[string]any, len(programIDs))
+	if len(programIDs) > 0 {
+		programRecords, err := app.Dao().FindRecordsByIds("programs", programIDs)
+		if err != nil {
+			return nil, err
+		}
+		expanded, err := expandChannels(app, programRecords)
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range programRecords {
+			programs[record.Id] = expanded[i]
+		}
+	}
+
+	reminders := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		reminder := map[string]any{
+			"id":         record.Id,
+			"program":    record.GetString("program"),
+			"remind_at":  record.GetString("remind_at"),
+			"start_time": record.GetString("start_time"),
+		}
+		if program, ok := programs[record.GetString("program")]; ok {
+			reminder["expand"] = map[string]any{"program": program}
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
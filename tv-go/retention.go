@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+)
+
+// DefaultRetentionDays is how long programs and fetch logs are kept when
+// nothing more specific is configured
+const DefaultRetentionDays = 30
+
+// RetentionPolicy decides how many days of data cleanupOldData keeps
+type RetentionPolicy struct {
+	// Programs on channels without a category override
+	ProgramDays int
+
+	// Per channel category program retention, e.g. sports kept longer
+	CategoryDays map[string]int
+
+	FetchLogDays int
+}
+
+// RetentionFromEnv builds a policy keeping programDays of programs, with
+// per-category overrides from CLEANUP_CATEGORY_DAYS ("sports:90,movies:180")
+// and fetch log retention from CLEANUP_FETCH_LOG_DAYS (default programDays)
+func RetentionFromEnv(programDays int) RetentionPolicy {
+	// Zero or negative days would delete every fetch log
+	fetchLogDays := envInt("CLEANUP_FETCH_LOG_DAYS", programDays)
+	if fetchLogDays < 1 {
+		log.Printf("⚠️  Ignoring invalid CLEANUP_FETCH_LOG_DAYS %d, keeping fetch logs for %d days", fetchLogDays, programDays)
+		fetchLogDays = programDays
+	}
+
+	return RetentionPolicy{
+		ProgramDays:  programDays,
+		CategoryDays: parseCategoryDays(os.Getenv("CLEANUP_CATEGORY_DAYS")),
+		FetchLogDays: fetchLogDays,
+	}
+}
+
+// parseCategoryDays parses "category:days" pairs separated by commas,
+// skipping unknown categories and invalid day counts
+func parseCategoryDays(value string) map[string]int {
+	categoryDays := make(map[string]int)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		category, daysStr, ok := strings.Cut(pair, ":")
+		category = strings.TrimSpace(category)
+		days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+		if !ok || err != nil || days < 1 {
+			log.Printf("⚠️  Ignoring invalid CLEANUP_CATEGORY_DAYS entry %q", pair)
+			continue
+		}
+		if !isChannelCategory(category) {
+			log.Printf("⚠️  Ignoring unknown channel category %q in CLEANUP_CATEGORY_DAYS", category)
+			continue
+		}
+
+		categoryDays[category] = days
+	}
+
+	return categoryDays
+}
+
+// programRetentionExp matches programs older than the retention of their
+// channel's category, falling back to ProgramDays
+func (p RetentionPolicy) programRetentionExp() dbx.Expression {
+	params := dbx.Params{"days": p.ProgramDays}
+	retention := "{:days}"
+
+	if len(p.CategoryDays) > 0 {
+		categories := make([]string, 0, len(p.CategoryDays))
+		for category := range p.CategoryDays {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+
+		var b strings.Builder
+		b.WriteString("CASE (SELECT category FROM channels WHERE channels.id = programs.channel)")
+		for i, category := range categories {
+			fmt.Fprintf(&b, " WHEN {:category%d} THEN {:days%d}", i, i)
+			params[fmt.Sprintf("category%d", i)] = category
+			params[fmt.Sprintf("days%d", i)] = p.CategoryDays[category]
+		}
+		b.WriteString(" ELSE {:days} END")
+		retention = b.String()
+	}
+
+	return dbx.NewExp("datetime(start_time) < datetime('now', '-' || ("+retention+") || ' days')", params)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+)
+
+func TestRetentionFromEnvFetchLogDays(t *testing.T) {
+	cases := []struct {
+		env  string
+		want int
+	}{
+		{"", 30},
+		{"14", 14},
+		{"1", 1},
+		{"0", 30},
+		{"-7", 30},
+		{"week", 30},
+	}
+
+	for _, tc := range cases {
+		t.Setenv("CLEANUP_FETCH_LOG_DAYS", tc.env)
+		if got := RetentionFromEnv(30).FetchLogDays; got != tc.want {
+			t.Errorf("CLEANUP_FETCH_LOG_DAYS=%q: FetchLogDays = %d, want %d", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestParseCategoryDays(t *testing.T) {
+	got := parseCategoryDays(" sports:90, movies:180,kids:0,weather:10,music,news:x,")
+	want := map[string]int{"sports": 90, "movies": 180}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCategoryDays = %v, want %v", got, want)
+	}
+
+	if got := parseCategoryDays(""); len(got) != 0 {
+		t.Errorf("parseCategoryDays(\"\") = %v, want empty", got)
+	}
+}
+
+func TestCleanupOldDataByCategory(t *testing.T) {
+	app := newTestApp(t)
+	t.Setenv("COLLECTOR_ARCHIVE_DIR", "")
+
+	daysAgo := func(days int) string {
+		return time.Now().UTC().AddDate(0, 0, -days).Format("2006-01-02 15:04:05.000Z")
+	}
+
+	insertRows(t, app, "channels",
+		dbx.Params{"id": "tv1", "name": "Yle TV1", "category": "public", "active": true},
+		dbx.Params{"id": "sport", "name": "Eurosport", "category": "sports", "active": true},
+		dbx.Params{"id": "film", "name": "Elokuvat", "category": "movies", "active": true},
+	)
+	programs := []struct {
+		id, channel string
+		age         int
+	}{
+		{"tv1-new", "tv1", 10},
+		{"tv1-old", "tv1", 40},
+		{"sport-mid", "sport", 40},
+		{"sport-old", "sport", 100},
+		{"film-old", "film", 200},
+		{"orphan-old", "gone", 40},
+	}
+	for _, p := range programs {
+		insertRows(t, app, "programs", dbx.Params{"id": p.id, "channel": p.channel, "name": p.id, "start_time": daysAgo(p.age)})
+	}
+	for id, age := range map[string]int{"log-new": 3, "log-mid": 10, "log-old": 20} {
+		insertRows(t, app, "fetch_logs", dbx.Params{"id": id, "channel": "tv1", "success": true, "created": daysAgo(age), "updated": daysAgo(age)})
+	}
+
+	policy := RetentionPolicy{ProgramDays: 30, CategoryDays: map[string]int{"sports": 90, "movies": 365}, FetchLogDays: 14}
+	if err := cleanupOldData(app, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := func(table string) []string {
+		var ids []string
+		if err := app.Dao().DB().Select("id").From(table).Column(&ids); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// Channels without an override, or without a channel, use ProgramDays
+	if got, want := remaining("programs"), []string{"film-old", "sport-mid", "tv1-new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("programs kept %v, want %v", got, want)
+	}
+	if got, want := remaining("fetch_logs"), []string{"log-mid", "log-new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch logs kept %v, want %v", got, want)
+	}
+}
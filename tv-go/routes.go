@@ -1,101 +1,632 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
-func setupCustomRoutes(app *pocketbase.PocketBase, e *core.ServeEvent) error {
-	// Health check endpoint
+// FetchJobName is the job name manual fetches are registered under, so they
+// can be looked up and canceled
+const FetchJobName = "manual_fetch"
+
+// HealthMaxFetchAge is how old the latest fetch log may be before health reports degraded
+const HealthMaxFetchAge = 26 * time.Hour
+
+// Window for /api/tv/fetch-status, in days
+const (
+	FetchStatusDefaultDays = 7
+	FetchStatusMaxDays     = 90
+)
+
+// StreamKeepAlive is how often the now stream sends a keep-alive comment
+const StreamKeepAlive = 30 * time.Second
+
+// Upcoming programs returned by /api/tv/channel/:id/now
+const (
+	DefaultUpNextLimit = 3
+	MaxUpNextLimit     = 20
+)
+
+// RelatedProgramsLimit caps each related list of /api/tv/program/:id
+const RelatedProgramsLimit = 10
+
+// Prime time hours for /api/tv/tonight, overridable with PRIMETIME_START
+// and PRIMETIME_END or ?start=&end=. An end before the start crosses midnight.
+const (
+	DefaultPrimeTimeStart = 20
+	DefaultPrimeTimeEnd   = 23
+)
+
+// Allowed ?days= ranges for the trigger routes, values outside are clamped
+const (
+	FetchMinDays   = 0
+	FetchMaxDays   = 14
+	CleanupMinDays = 1
+	CleanupMaxDays = 365
+)
+
+// fetchDayStatus is one day of aggregated fetch_logs activity
+type fetchDayStatus struct {
+	Date          string  `db:"day" json:"date"`
+	Succeeded     int     `db:"succeeded" json:"succeeded"`
+	Failed        int     `db:"failed" json:"failed"`
+	ProgramsCount int     `db:"programs_count" json:"programs_count"`
+	AvgDurationMs float64 `db:"avg_duration_ms" json:"avg_duration_ms"`
+}
+
+func setupCustomRoutes(app *pocketbase.PocketBase, e *core.ServeEvent, jobs *JobTracker) error {
+	// Health check endpoint, reports degraded when the DB is unreachable or
+	// the last fetch failed or is stale
 	e.Router.GET("/api/health", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]interface{}{
-			"status":    "ok",
-			"timestamp": time.Now().Format(time.RFC3339),
+		status := "ok"
+		dbStatus := "ok"
+		var lastFetch interface{}
+
+		if _, err := app.Dao().DB().NewQuery("SELECT 1").Execute(); err != nil {
+			status = "degraded"
+			dbStatus = "error"
+		} else {
+			lastLog := &models.Record{}
+			err := app.Dao().RecordQuery("fetch_logs").
+				AndWhere(notPurgeLog).
+				OrderBy("created DESC").
+				Limit(1).
+				One(lastLog)
+
+			if err != nil {
+				status = "degraded"
+			} else {
+				created := lastLog.GetDateTime("created").Time()
+				lastFetch = created.Format(time.RFC3339)
+				if !lastLog.GetBool("success") || time.Since(created) > HealthMaxFetchAge {
+					status = "degraded"
+				}
+			}
+		}
+
+		code := http.StatusOK
+		if status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+
+		return c.JSON(code, map[string]interface{}{
+			"status":     status,
+			"db":         dbStatus,
+			"last_fetch": lastFetch,
+			"timestamp":  time.Now().Format(time.RFC3339),
 		})
 	})
 
 	// Get programs currently airing (what's on now)
 	e.Router.GET("/api/tv/now", func(c echo.Context) error {
-		now := time.Now().Format(time.RFC3339)
+		grid, err := nowPlaying(app, c)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, grid)
+	})
+
+	// Stream the now grid as server-sent events, re-evaluated at every minute
+	// boundary and pushed only when a program transition happened
+	e.Router.GET("/api/tv/stream/now", func(c echo.Context) error {
+		grid, err := nowPlaying(app, c)
+		if err != nil {
+			return err
+		}
+
+		w := c.Response()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		send := func(grid []map[string]any) error {
+			data, err := json.Marshal(grid)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: now\ndata: %s\n\n", data); err != nil {
+				return err
+			}
+			w.Flush()
+			return nil
+		}
+
+		if err := send(grid); err != nil {
+			return nil
+		}
+		last := gridSignature(grid)
+
+		keepAlive := time.NewTicker(StreamKeepAlive)
+		defer keepAlive.Stop()
+		minute := time.NewTimer(untilNextMinute())
+		defer minute.Stop()
+
+		ctx := c.Request().Context()
+		for {
+			select {
+			case <-ctx.Done():
+				// Client went away
+				return nil
+
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return nil
+				}
+				w.Flush()
+
+			case <-minute.C:
+				minute.Reset(untilNextMinute())
+
+				grid, err := nowPlaying(app, c)
+				if err != nil {
+					app.Logger().Error("Now stream refresh failed", "error", err)
+					continue
+				}
+				if signature := gridSignature(grid); signature != last {
+					last = signature
+					if err := send(grid); err != nil {
+						return nil
+					}
+				}
+			}
+		}
+	})
+
+	// Get tonight's prime time programs (default 20:00-23:00)
+	e.Router.GET("/api/tv/tonight", func(c echo.Context) error {
+		startHour, endHour, err := parsePrimeTime(c)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
+		}
+		start, end := primeTimeWindow(time.Now(), startHour, endHour)
+
+		startDT, err := types.ParseDateTime(start)
+		if err != nil {
+			return apis.NewApiError(500, "Invalid window start", err)
+		}
+		endDT, err := types.ParseDateTime(end)
+		if err != nil {
+			return apis.NewApiError(500, "Invalid window end", err)
+		}
+
+		filter := "start_time >= {:start} && start_time <= {:end}"
+		params := map[string]any{
+			"start": startDT.String(),
+			"end":   endDT.String(),
+		}
+		filter, err = withChannelCategory(c, filter, params)
+		if err != nil {
+			return err
+		}
 
 		records, err := app.Dao().FindRecordsByFilter(
 			"programs",
-			"start_time <= {:now} && end_time >= {:now}",
-			"-start_time",
-			100,
+			filter,
+			"start_time",
+			200,
 			0,
-			map[string]any{"now": now},
+			params,
 		)
 
 		if err != nil {
 			return apis.NewApiError(500, "Failed to fetch programs", err)
 		}
 
-		// Expand channel relation
-		expandedRecords := make([]map[string]any, 0, len(records))
-		for _, record := range records {
-			data := record.PublicExport()
+		expandedRecords, err := expandChannels(app, records)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to expand channels", err)
+		}
 
-			// Get channel info
-			if channelID := record.GetString("channel"); channelID != "" {
-				if channel, err := app.Dao().FindRecordById("channels", channelID); err == nil {
-					data["expand"] = map[string]any{
-						"channel": channel.PublicExport(),
-					}
-				}
+		// The effective window goes in headers, the body stays the plain
+		// array clients already expect
+		c.Response().Header().Set("X-Prime-Time-Start", start.Format(time.RFC3339))
+		c.Response().Header().Set("X-Prime-Time-End", end.Format(time.RFC3339))
+		c.Response().Header().Set("Access-Control-Expose-Headers", "X-Prime-Time-Start, X-Prime-Time-End")
+		return c.JSON(http.StatusOK, expandedRecords)
+	})
+
+	// List channels with their upcoming program counts, active only unless
+	// an admin asks for ?includeInactive=true
+	e.Router.GET("/api/tv/channels", func(c echo.Context) error {
+		filter := "active = true"
+		params := map[string]any{}
+		if c.QueryParam("includeInactive") == "true" {
+			admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
+			if admin == nil {
+				return apis.NewForbiddenError("Admin authentication required for includeInactive", nil)
+			}
+			filter = "id != ''"
+		}
+		if category := c.QueryParam("category"); category != "" {
+			if !isChannelCategory(category) {
+				return apis.NewBadRequestError(fmt.Sprintf("Unknown category %q", category), nil)
 			}
+			filter += " && category = {:category}"
+			params["category"] = category
+		}
 
-			expandedRecords = append(expandedRecords, data)
+		records, err := app.Dao().FindRecordsByFilter("channels", filter, "show_order", 0, 0, params)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch channels", err)
 		}
 
-		return c.JSON(http.StatusOK, expandedRecords)
+		counts, err := upcomingCounts.Get(app)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to count upcoming programs", err)
+		}
+
+		channels := make([]map[string]any, 0, len(records))
+		for _, record := range records {
+			channels = append(channels, map[string]any{
+				"id":                record.Id,
+				"name":              record.GetString("name"),
+				"category":          record.GetString("category"),
+				"logo_url":          record.GetString("logo_url"),
+				"show_order":        record.GetInt("show_order"),
+				"active":            record.GetBool("active"),
+				"upcoming_programs": counts[record.Id],
+			})
+		}
+
+		return c.JSON(http.StatusOK, channels)
 	})
 
-	// Get tonight's prime time programs (20:00-23:00)
-	e.Router.GET("/api/tv/tonight", func(c echo.Context) error {
-		today := time.Now()
-		start := time.Date(today.Year(), today.Month(), today.Day(), 20, 0, 0, 0, today.Location())
-		end := time.Date(today.Year(), today.Month(), today.Day(), 23, 0, 0, 0, today.Location())
+	// Current and next programs on one channel
+	e.Router.GET("/api/tv/channel/:id/now", func(c echo.Context) error {
+		channel, err := app.Dao().FindRecordById("channels", c.PathParam("id"))
+		if err != nil || !channel.GetBool("active") {
+			return apis.NewNotFoundError("Channel not found", err)
+		}
 
-		records, err := app.Dao().FindRecordsByFilter(
+		limit, err := parseLimit(c, DefaultUpNextLimit, MaxUpNextLimit)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
+		}
+
+		now := time.Now()
+		params := map[string]any{
+			"channel": channel.Id,
+			"now":     types.NowDateTime().String(),
+		}
+
+		var current interface{}
+		airing, err := app.Dao().FindRecordsByFilter(
 			"programs",
-			"start_time >= {:start} && start_time <= {:end}",
+			"channel = {:channel} && start_time <= {:now} && end_time > {:now}",
+			"-start_time",
+			1,
+			0,
+			params,
+		)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch current program", err)
+		}
+		if len(airing) > 0 {
+			data := airing[0].PublicExport()
+			remaining := airing[0].GetDateTime("end_time").Time().Sub(now)
+			data["minutes_remaining"] = int(math.Ceil(remaining.Minutes()))
+			current = data
+		}
+
+		upcoming, err := app.Dao().FindRecordsByFilter(
+			"programs",
+			"channel = {:channel} && start_time > {:now}",
 			"start_time",
-			200,
+			limit,
 			0,
-			map[string]any{
-				"start": start.Format(time.RFC3339),
-				"end":   end.Format(time.RFC3339),
-			},
+			params,
 		)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch upcoming programs", err)
+		}
 
+		next := make([]map[string]any, 0, len(upcoming))
+		for _, record := range upcoming {
+			next = append(next, record.PublicExport())
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"channel": channel.PublicExport(),
+			"current": current,
+			"next":    next,
+		})
+	})
+
+	// Channel logo, proxied and cached on disk, or a placeholder when the
+	// channel has none or upstream fails
+	e.Router.GET("/api/tv/channel/:id/logo", func(c echo.Context) error {
+		channel, err := app.Dao().FindRecordById("channels", c.PathParam("id"))
 		if err != nil {
-			return apis.NewApiError(500, "Failed to fetch programs", err)
+			return apis.NewNotFoundError("Channel not found", err)
+		}
+
+		logo, err := logoCache.Get(c.Request().Context(), app, channel)
+		if err != nil {
+			if !errors.Is(err, errNoLogo) {
+				app.Logger().Warn("Channel logo unavailable", "channel", channel.Id, "error", err)
+			}
+			c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(LogoPlaceholderMaxAge.Seconds())))
+			return c.Blob(http.StatusOK, "image/svg+xml", placeholderLogo(channel.GetString("name")))
+		}
+
+		c.Response().Header().Set("ETag", logo.ETag)
+		c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(logoCache.ttl.Seconds())))
+		if etagMatches(c.Request().Header.Get("If-None-Match"), logo.ETag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+		return c.Blob(http.StatusOK, logo.ContentType, logo.Data)
+	})
+
+	// Browse series by name and active flag, most recently seen first
+	e.Router.GET("/api/tv/series", func(c echo.Context) error {
+		page, perPage, err := parsePagination(c, 30, 100)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
+		}
+
+		conditions := []dbx.Expression{}
+		if q := strings.TrimSpace(c.QueryParam("q")); q != "" {
+			// dbx.Like escapes % and _ in q
+			conditions = append(conditions, dbx.Like("name", q))
 		}
+		if active := c.QueryParam("active"); active != "" {
+			parsed, err := strconv.ParseBool(active)
+			if err != nil {
+				return apis.NewBadRequestError("Invalid active, must be true or false", err)
+			}
+			conditions = append(conditions, dbx.HashExp{"active": parsed})
+		}
+		where := dbx.And(conditions...)
 
-		// Expand channel relation
-		expandedRecords := make([]map[string]any, 0, len(records))
+		var totalItems int
+		err = app.Dao().DB().Select("count(*)").
+			From("series").
+			Where(where).
+			Row(&totalItems)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to count series", err)
+		}
+
+		records := []*models.Record{}
+		err = app.Dao().RecordQuery("series").
+			AndWhere(where).
+			OrderBy("last_seen DESC").
+			Limit(int64(perPage)).
+			Offset(int64((page - 1) * perPage)).
+			All(&records)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch series", err)
+		}
+
+		counts, err := seriesProgramCounts(app, records)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to count episodes", err)
+		}
+
+		items := make([]map[string]any, 0, len(records))
 		for _, record := range records {
-			data := record.PublicExport()
+			items = append(items, map[string]any{
+				"id":            record.Id,
+				"name":          record.GetString("name"),
+				"description":   record.GetString("description"),
+				"active":        record.GetBool("active"),
+				"first_seen":    record.GetString("first_seen"),
+				"last_seen":     record.GetString("last_seen"),
+				"episode_count": counts[record.Id],
+			})
+		}
 
-			if channelID := record.GetString("channel"); channelID != "" {
-				if channel, err := app.Dao().FindRecordById("channels", channelID); err == nil {
-					data["expand"] = map[string]any{
-						"channel": channel.PublicExport(),
-					}
-				}
+		return c.JSON(http.StatusOK, newListResult(page, perPage, totalItems, items))
+	})
+
+	// Search upcoming programs by name, flat or grouped by series
+	e.Router.GET("/api/tv/search", func(c echo.Context) error {
+		q := strings.TrimSpace(c.QueryParam("q"))
+		if q == "" {
+			return apis.NewBadRequestError("Missing search query q", nil)
+		}
+		page, perPage, err := parsePagination(c, 30, 100)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
+		}
+
+		// Program genre/category, free text as stored from the API
+		category := c.QueryParam("category")
+
+		var items []map[string]any
+		var totalItems int
+		switch groupBy := c.QueryParam("groupBy"); groupBy {
+		case SearchGroupNone:
+			items, totalItems, err = searchPrograms(app, q, c.QueryParam("series"), category, page, perPage)
+		case SearchGroupSeries:
+			items, totalItems, err = searchProgramsBySeries(app, q, category, page, perPage)
+		default:
+			return apis.NewBadRequestError(fmt.Sprintf("Invalid groupBy %q, must be %s", groupBy, SearchGroupSeries), nil)
+		}
+		if err != nil {
+			return apis.NewApiError(500, "Failed to search programs", err)
+		}
+
+		return c.JSON(http.StatusOK, newListResult(page, perPage, totalItems, items))
+	})
+
+	// The authenticated user's favorite series. Every query is scoped to the
+	// user, so nobody sees or changes another user's favorites.
+	favoriteRoutes := e.Router.Group("/api/tv/favorites", apis.RequireRecordAuth("users"))
+
+	favoriteRoutes.GET("", func(c echo.Context) error {
+		user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+		favorites, err := listFavorites(app, user.Id)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch favorites", err)
+		}
+		return c.JSON(http.StatusOK, favorites)
+	})
+
+	favoriteRoutes.POST("", func(c echo.Context) error {
+		user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+		var body struct {
+			Series string `json:"series"`
+		}
+		if err := c.Bind(&body); err != nil || body.Series == "" {
+			return apis.NewBadRequestError("Body must be {\"series\": \"<series id>\"}", err)
+		}
+
+		series, err := app.Dao().FindRecordById("series", body.Series)
+		if err != nil {
+			return apis.NewNotFoundError("Series not found", err)
+		}
+		favorite, created, err := addFavorite(app, user.Id, series)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to add favorite", err)
+		}
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		return c.JSON(status, favoriteResponse(favorite, series))
+	})
+
+	favoriteRoutes.DELETE("/:seriesId", func(c echo.Context) error {
+		user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+		favorite := findFavorite(app, user.Id, c.PathParam("seriesId"))
+		if favorite == nil {
+			return apis.NewNotFoundError("Series is not a favorite", nil)
+		}
+		if err := app.Dao().DeleteRecord(favorite); err != nil {
+			return apis.NewApiError(500, "Failed to remove favorite", err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	// Next airings of all favorite series, across channels
+	favoriteRoutes.GET("/upcoming", func(c echo.Context) error {
+		user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+		limit, err := parseLimit(c, DefaultFavoritesUpcomingLimit, MaxFavoritesUpcomingLimit)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
+		}
+
+		programs, err := upcomingFavorites(app, user.Id, limit)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch upcoming favorites", err)
+		}
+		return c.JSON(http.StatusOK, programs)
+	})
+
+	reminderRoutes := e.Router.Group("/api/tv/reminders", apis.RequireRecordAuth("users"))
+
+	// Reminders that are due for programs that haven't started yet
+	reminderRoutes.GET("/pending", func(c echo.Context) error {
+		user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+		reminders, err := pendingReminders(app, user.Id)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch reminders", err)
+		}
+		return c.JSON(http.StatusOK, reminders)
+	})
+
+	// Dismissed reminders are kept as such, so the job doesn't recreate them
+	reminderRoutes.DELETE("/:id", func(c echo.Context) error {
+		user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+		reminder, err := app.Dao().FindRecordById("reminders", c.PathParam("id"))
+		if err != nil || reminder.GetString("user") != user.Id || reminder.GetBool("dismissed") {
+			return apis.NewNotFoundError("Reminder not found", err)
+		}
+		if err := dismissReminder(app, reminder); err != nil {
+			return apis.NewApiError(500, "Failed to dismiss reminder", err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	// Program details with its channel and series, upcoming episodes of the
+	// series and what airs in the same slot on other channels
+	e.Router.GET("/api/tv/program/:id", func(c echo.Context) error {
+		program, err := app.Dao().FindRecordById("programs", c.PathParam("id"))
+		if err != nil {
+			return apis.NewNotFoundError("Program not found", err)
+		}
+
+		data := program.PublicExport()
+		expand := map[string]any{}
+		if channel, err := app.Dao().FindRecordById("channels", program.GetString("channel")); err == nil {
+			expand["channel"] = channel.PublicExport()
+		}
+		seriesID := program.GetString("series")
+		if seriesID != "" {
+			if series, err := app.Dao().FindRecordById("series", seriesID); err == nil {
+				expand["series"] = series.PublicExport()
 			}
+		}
+		data["expand"] = expand
 
-			expandedRecords = append(expandedRecords, data)
+		params := map[string]any{
+			"id":      program.Id,
+			"series":  seriesID,
+			"channel": program.GetString("channel"),
+			"now":     types.NowDateTime().String(),
+			"start":   program.GetDateTime("start_time").String(),
+			"end":     program.GetDateTime("end_time").String(),
 		}
 
-		return c.JSON(http.StatusOK, expandedRecords)
+		upcomingEpisodes := []map[string]any{}
+		if seriesID != "" {
+			records, err := app.Dao().FindRecordsByFilter(
+				"programs",
+				"series = {:series} && id != {:id} && start_time > {:now}",
+				"start_time",
+				RelatedProgramsLimit,
+				0,
+				params,
+			)
+			if err != nil {
+				return apis.NewApiError(500, "Failed to fetch upcoming episodes", err)
+			}
+			if upcomingEpisodes, err = expandChannels(app, records); err != nil {
+				return apis.NewApiError(500, "Failed to expand channels", err)
+			}
+		}
+
+		records, err := app.Dao().FindRecordsByFilter(
+			"programs",
+			"channel != {:channel} && channel.active = true && start_time < {:end} && end_time > {:start}",
+			"start_time",
+			RelatedProgramsLimit,
+			0,
+			params,
+		)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch programs in the same slot", err)
+		}
+		sameSlot, err := expandChannels(app, records)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to expand channels", err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"program":           data,
+			"upcoming_episodes": upcomingEpisodes,
+			"same_slot":         sameSlot,
+		})
 	})
 
 	// Get schedule for a specific channel and date
@@ -109,78 +640,206 @@ func setupCustomRoutes(app *pocketbase.PocketBase, e *core.ServeEvent) error {
 			return apis.NewBadRequestError("Invalid date format. Use YYYY-MM-DD", err)
 		}
 
+		page, perPage, err := parsePagination(c, 100, 200)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
+		}
+
 		start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 		end := start.AddDate(0, 0, 1)
 
-		records, err := app.Dao().FindRecordsByFilter(
-			"programs",
-			"channel = {:channel} && start_time >= {:start} && start_time < {:end}",
-			"start_time",
-			200,
-			0,
-			map[string]any{
-				"channel": channelID,
-				"start":   start.Format(time.RFC3339),
-				"end":     end.Format(time.RFC3339),
-			},
-		)
+		// One expression for the count and the page, so they can't disagree
+		conditions := []dbx.Expression{
+			dbx.HashExp{"channel": channelID},
+			dbx.NewExp("start_time >= {:start} AND start_time < {:end}", dbx.Params{
+				"start": start.Format(time.RFC3339),
+				"end":   end.Format(time.RFC3339),
+			}),
+		}
+		if category := c.QueryParam("category"); category != "" {
+			conditions = append(conditions, dbx.HashExp{"category": category})
+		}
+		where := dbx.And(conditions...)
 
+		var totalItems int
+		err = app.Dao().DB().Select("count(*)").
+			From("programs").
+			Where(where).
+			Row(&totalItems)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to count programs", err)
+		}
+
+		records := []*models.Record{}
+		err = app.Dao().RecordQuery("programs").
+			AndWhere(where).
+			OrderBy("start_time ASC").
+			Limit(int64(perPage)).
+			Offset(int64((page - 1) * perPage)).
+			All(&records)
 		if err != nil {
 			return apis.NewApiError(500, "Failed to fetch programs", err)
 		}
 
-		// Export records
-		exportedRecords := make([]map[string]any, 0, len(records))
-		for _, record := range records {
-			exportedRecords = append(exportedRecords, record.PublicExport())
+		expandedRecords, err := expandChannels(app, records)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to expand channels", err)
 		}
 
-		return c.JSON(http.StatusOK, exportedRecords)
+		return c.JSON(http.StatusOK, newListResult(page, perPage, totalItems, expandedRecords))
 	})
 
+	// Every /api/admin route requires an admin, checked once by the group
+	adminRoutes := e.Router.Group("/api/admin", requireAdmin)
+
 	// Manual trigger for data collection (admin only)
-	e.Router.POST("/api/admin/trigger/fetch", func(c echo.Context) error {
-		admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
-		if admin == nil {
-			return apis.NewForbiddenError("Admin authentication required", nil)
+	adminRoutes.POST("/trigger/fetch", func(c echo.Context) error {
+		daysAhead, err := parseDays(c, 7, FetchMinDays, FetchMaxDays)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
 		}
 
-		daysAhead := 7
-		if days := c.QueryParam("days"); days != "" {
-			// Parse days parameter if provided
-			var d int
-			if _, err := echo.QueryParamsBinder(c).Int("days", &d).BindError(); err == nil {
-				daysAhead = d
+		opts := DefaultFetchOptions(daysAhead)
+		opts.ForceRefresh = c.QueryParam("force") == "true"
+
+		// ?source= overrides TV_SOURCE for this run
+		collector := NewTVCollector(app)
+		if source := c.QueryParam("source"); source != "" {
+			if err := collector.UseSource(source); err != nil {
+				return apis.NewBadRequestError(err.Error(), err)
 			}
 		}
 
-		// Run in background
-		go func() {
-			collector := NewTVCollector(app)
-			if err := collector.FetchAllPrograms(daysAhead); err != nil {
+		// Run in background, one manual fetch at a time
+		started := jobs.GoCancelable(FetchJobName, func(ctx context.Context) {
+			summary, err := collector.FetchAllPrograms(ctx, opts)
+			if err != nil {
 				app.Logger().Error("Manual fetch failed", "error", err)
 			}
-		}()
+			app.Logger().Info("Manual fetch summary", summary.LogAttrs()...)
+		})
+		if !started {
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+				"message": "A fetch job is already running",
+			})
+		}
 
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"message":    "Fetch job triggered",
 			"days_ahead": daysAhead,
+			"force":      opts.ForceRefresh,
+			"source":     collector.Source.Name(),
 		})
 	})
 
-	// Manual trigger for channel update (admin only)
-	e.Router.POST("/api/admin/trigger/update-channels", func(c echo.Context) error {
-		admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
-		if admin == nil {
-			return apis.NewForbiddenError("Admin authentication required", nil)
+	// Outcome of the most recent fetch run (admin only)
+	adminRoutes.GET("/fetch/summary", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"running": jobs.Running(FetchJobName),
+			"last":    lastFetchResult(),
+		})
+	})
+
+	// Progress of the running fetch, plus the last finished one (admin only)
+	adminRoutes.GET("/trigger/status", func(c echo.Context) error {
+		state := fetchProgress.Get()
+		var startedAt interface{}
+		if state.Running {
+			startedAt = state.StartedAt.UTC().Format(time.RFC3339)
 		}
 
-		go func() {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"running":         state.Running,
+			"started_at":      startedAt,
+			"channels_done":   state.ChannelsDone,
+			"channels_total":  state.ChannelsTotal,
+			"current_channel": state.CurrentChannel,
+			"last":            lastFetchResult(),
+		})
+	})
+
+	// Cancel a running manual fetch (admin only)
+	adminRoutes.POST("/trigger/cancel", func(c echo.Context) error {
+		if !jobs.Cancel(FetchJobName) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+				"message":  "No fetch job is running",
+				"canceled": false,
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":  "Fetch job canceled",
+			"canceled": true,
+		})
+	})
+
+	// Delete programs airing between two dates (admin only)
+	adminRoutes.POST("/purge", func(c echo.Context) error {
+		// Both bounds are required so a purge can never cover everything
+		fromStr, toStr := c.QueryParam("from"), c.QueryParam("to")
+		if fromStr == "" || toStr == "" {
+			return apis.NewBadRequestError("Both from and to are required (YYYY-MM-DD)", nil)
+		}
+		from, err := time.ParseInLocation("2006-01-02", fromStr, time.Local)
+		if err != nil {
+			return apis.NewBadRequestError("Invalid from date. Use YYYY-MM-DD", err)
+		}
+		to, err := time.ParseInLocation("2006-01-02", toStr, time.Local)
+		if err != nil {
+			return apis.NewBadRequestError("Invalid to date. Use YYYY-MM-DD", err)
+		}
+		if to.Before(from) {
+			return apis.NewBadRequestError("to must be on or after from", nil)
+		}
+
+		// to is inclusive
+		removed, err := purgePrograms(app, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			return apis.NewApiError(500, "Failed to purge programs", err)
+		}
+		statsCache.Invalidate()
+		upcomingCounts.Invalidate()
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Programs purged",
+			"from":    fromStr,
+			"to":      toStr,
+			"removed": removed,
+		})
+	})
+
+	// Find overlapping programs per channel and drop duplicates (admin only).
+	// Dry run unless ?dryRun=false.
+	adminRoutes.POST("/repair/overlaps", func(c echo.Context) error {
+		dryRun := true
+		if d := c.QueryParam("dryRun"); d != "" {
+			parsed, err := strconv.ParseBool(d)
+			if err != nil {
+				return apis.NewBadRequestError("Invalid dryRun, must be true or false", err)
+			}
+			dryRun = parsed
+		}
+
+		report, err := repairOverlaps(app, dryRun)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to repair overlapping programs", err)
+		}
+		if !dryRun && report.Merged > 0 {
+			statsCache.Invalidate()
+			upcomingCounts.Invalidate()
+		}
+
+		return c.JSON(http.StatusOK, report)
+	})
+
+	// Manual trigger for channel update (admin only)
+	adminRoutes.POST("/trigger/update-channels", func(c echo.Context) error {
+		jobs.Go("manual_update_channels", func(ctx context.Context) {
 			collector := NewTVCollector(app)
-			if err := collector.UpdateChannelList(); err != nil {
+			if err := collector.UpdateChannelList(ctx); err != nil {
 				app.Logger().Error("Channel update failed", "error", err)
 			}
-		}()
+		})
 
 		return c.JSON(http.StatusOK, map[string]string{
 			"message": "Channel update job triggered",
@@ -188,69 +847,428 @@ func setupCustomRoutes(app *pocketbase.PocketBase, e *core.ServeEvent) error {
 	})
 
 	// Manual trigger for cleanup (admin only)
-	e.Router.POST("/api/admin/trigger/cleanup", func(c echo.Context) error {
-		admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
-		if admin == nil {
-			return apis.NewForbiddenError("Admin authentication required", nil)
-		}
-
-		days := 30
-		if d := c.QueryParam("days"); d != "" {
-			var parsedDays int
-			if _, err := echo.QueryParamsBinder(c).Int("days", &parsedDays).BindError(); err == nil {
-				days = parsedDays
-			}
+	adminRoutes.POST("/trigger/cleanup", func(c echo.Context) error {
+		days, err := parseDays(c, DefaultRetentionDays, CleanupMinDays, CleanupMaxDays)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
 		}
+		policy := RetentionFromEnv(days)
 
-		go func() {
-			if err := cleanupOldData(app, days); err != nil {
+		jobs.Go("manual_cleanup", func(ctx context.Context) {
+			if err := cleanupOldData(app, policy); err != nil {
 				app.Logger().Error("Cleanup failed", "error", err)
 			}
-		}()
+		})
 
 		return c.JSON(http.StatusOK, map[string]interface{}{
-			"message": "Cleanup job triggered",
-			"days":    days,
+			"message":        "Cleanup job triggered",
+			"days":           days,
+			"category_days":  policy.CategoryDays,
+			"fetch_log_days": policy.FetchLogDays,
 		})
 	})
 
-	// Get statistics
-	e.Router.GET("/api/tv/stats", func(c echo.Context) error {
-		stats := make(map[string]interface{})
+	// Highly rated programs and premieres between two dates, grouped by day
+	e.Router.GET("/api/tv/highlights", func(c echo.Context) error {
+		today := time.Now()
+		from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+		to := from.AddDate(0, 0, 6)
 
-		// Count programs
-		programCount, err := app.Dao().DB().Select("count(*)").
-			From("programs").
-			Limit(1).
-			Build().
-			Execute()
-		if err == nil {
-			stats["total_programs"] = programCount
+		if f := c.QueryParam("from"); f != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", f, time.Local)
+			if err != nil {
+				return apis.NewBadRequestError("Invalid from date. Use YYYY-MM-DD", err)
+			}
+			from = parsed
+			to = from.AddDate(0, 0, 6)
+		}
+		if t := c.QueryParam("to"); t != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", t, time.Local)
+			if err != nil {
+				return apis.NewBadRequestError("Invalid to date. Use YYYY-MM-DD", err)
+			}
+			to = parsed
+		}
+		if to.Before(from) || to.Sub(from) > MaxHighlightsRangeDays*24*time.Hour {
+			return apis.NewBadRequestError(fmt.Sprintf("to must be on or after from, at most %d days apart", MaxHighlightsRangeDays), nil)
 		}
 
-		// Count channels
-		channelCount, err := app.Dao().DB().Select("count(*)").
-			From("channels").
-			Where(app.Dao().DB().NewExp("active = {:active}", map[string]any{"active": true})).
-			Limit(1).
-			Build().
-			Execute()
-		if err == nil {
-			stats["total_channels"] = channelCount
+		perDay, err := parseLimit(c, DefaultHighlightsPerDay, MaxHighlightsPerDay)
+		if err != nil {
+			return apis.NewBadRequestError(err.Error(), err)
 		}
 
-		// Count series
-		seriesCount, err := app.Dao().DB().Select("count(*)").
-			From("series").
+		minRating := envInt("HIGHLIGHTS_MIN_RATING", DefaultHighlightsMinRating)
+
+		// to is inclusive
+		days, err := findHighlights(app, from, to.AddDate(0, 0, 1), minRating, perDay)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to fetch highlights", err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"from":       from.Format("2006-01-02"),
+			"to":         to.Format("2006-01-02"),
+			"min_rating": minRating,
+			"days":       days,
+		})
+	})
+
+	// Aggregated fetch_logs activity per day, most recent first
+	e.Router.GET("/api/tv/fetch-status", func(c echo.Context) error {
+		days := FetchStatusDefaultDays
+		if d := c.QueryParam("days"); d != "" {
+			parsed, err := strconv.Atoi(d)
+			if err != nil || parsed < 1 {
+				return apis.NewBadRequestError("Invalid days, must be >= 1", err)
+			}
+			days = parsed
+		}
+		if days > FetchStatusMaxDays {
+			days = FetchStatusMaxDays
+		}
+
+		since := time.Now().UTC().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+
+		dailyStatus := []fetchDayStatus{}
+		err := app.Dao().DB().NewQuery(`
+			SELECT
+				date(created) AS day,
+				SUM(CASE WHEN success THEN 1 ELSE 0 END) AS succeeded,
+				SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failed,
+				COALESCE(SUM(programs_count), 0) AS programs_count,
+				COALESCE(AVG(duration_ms), 0) AS avg_duration_ms
+			FROM fetch_logs
+			WHERE created >= {:since} AND error_message NOT LIKE {:purgeMarker}
+			GROUP BY day
+			ORDER BY day DESC
+		`).Bind(dbx.Params{"since": since, "purgeMarker": PurgeLogMarker + "%"}).All(&dailyStatus)
+		if err != nil {
+			return apis.NewApiError(500, "Failed to aggregate fetch logs", err)
+		}
+
+		var lastFailure interface{}
+		failure := &models.Record{}
+		err = app.Dao().RecordQuery("fetch_logs").
+			AndWhere(dbx.HashExp{"success": false}).
+			OrderBy("created DESC").
 			Limit(1).
-			Build().
-			Execute()
+			One(failure)
 		if err == nil {
-			stats["total_series"] = seriesCount
+			lastFailure = map[string]interface{}{
+				"channel":       failure.GetString("channel"),
+				"target_date":   failure.GetString("target_date"),
+				"error_message": failure.GetString("error_message"),
+				"created":       failure.GetDateTime("created").Time().Format(time.RFC3339),
+			}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return apis.NewApiError(500, "Failed to fetch last failure", err)
 		}
 
-		return c.JSON(http.StatusOK, stats)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"days":         days,
+			"daily":        dailyStatus,
+			"last_failure": lastFailure,
+		})
+	})
+
+	// Get statistics (cached, see stats.go)
+	e.Router.GET("/api/tv/stats", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, statsCache.Get(app))
 	})
 
 	return nil
 }
+
+// nowPlaying builds the "what's on now" grid shared by /api/tv/now and the
+// now stream
+func nowPlaying(app *pocketbase.PocketBase, c echo.Context) ([]map[string]any, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	filter := "start_time <= {:now} && end_time >= {:now}"
+	params := map[string]any{"now": now}
+	filter, err := withChannelCategory(c, filter, params)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := app.Dao().FindRecordsByFilter(
+		"programs",
+		filter,
+		"-start_time",
+		100,
+		0,
+		params,
+	)
+
+	if err != nil {
+		return nil, apis.NewApiError(500, "Failed to fetch programs", err)
+	}
+
+	expandedRecords, err := expandChannels(app, records)
+	if err != nil {
+		return nil, apis.NewApiError(500, "Failed to expand channels", err)
+	}
+
+	return expandedRecords, nil
+}
+
+// gridSignature identifies which programs a now grid contains
+func gridSignature(grid []map[string]any) string {
+	ids := make([]string, 0, len(grid))
+	for _, program := range grid {
+		ids = append(ids, fmt.Sprint(program["id"]))
+	}
+	return strings.Join(ids, ",")
+}
+
+// untilNextMinute is the time left until the next wall-clock minute
+func untilNextMinute() time.Duration {
+	now := time.Now()
+	return now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+}
+
+// withChannelCategory adds a channel.category condition to a programs filter
+// when ?category is set, rejecting values outside ChannelCategories
+func withChannelCategory(c echo.Context, filter string, params map[string]any) (string, error) {
+	category := c.QueryParam("category")
+	if category == "" {
+		return filter, nil
+	}
+	if !isChannelCategory(category) {
+		return "", apis.NewBadRequestError(fmt.Sprintf("Unknown category %q", category), nil)
+	}
+
+	params["channelCategory"] = category
+	return filter + " && channel.category = {:channelCategory}", nil
+}
+
+// parsePagination reads ?page and ?perPage, applying defaults and capping perPage
+func parsePagination(c echo.Context, defaultPerPage, maxPerPage int) (int, int, error) {
+	page := 1
+	if p := c.QueryParam("page"); p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil || parsed < 1 {
+			return 0, 0, fmt.Errorf("invalid page %q, must be >= 1", p)
+		}
+		page = parsed
+	}
+
+	perPage := defaultPerPage
+	if pp := c.QueryParam("perPage"); pp != "" {
+		parsed, err := strconv.Atoi(pp)
+		if err != nil || parsed < 1 {
+			return 0, 0, fmt.Errorf("invalid perPage %q, must be >= 1", pp)
+		}
+		perPage = parsed
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return page, perPage, nil
+}
+
+// parseLimit reads ?limit, rejecting values below 1 and capping it at max
+func parseLimit(c echo.Context, def, max int) (int, error) {
+	limit := def
+	if l := c.QueryParam("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 {
+			return 0, fmt.Errorf("invalid limit %q, must be >= 1", l)
+		}
+		limit = parsed
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, nil
+}
+
+// parseDays reads ?days, rejecting non-numeric values and clamping the
+// result to [min, max]
+func parseDays(c echo.Context, def, min, max int) (int, error) {
+	days := def
+	if d := c.QueryParam("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid days %q, must be a number", d)
+		}
+		days = parsed
+	}
+
+	if days < min {
+		days = min
+	}
+	if days > max {
+		days = max
+	}
+	return days, nil
+}
+
+// requireAdmin rejects requests without an authenticated admin with 403
+func requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
+		if admin == nil {
+			return apis.NewForbiddenError("Admin authentication required", nil)
+		}
+		return next(c)
+	}
+}
+
+// lastFetchResult describes the most recent finished fetch for the admin
+// routes, nil when none has finished since startup
+func lastFetchResult() interface{} {
+	summary, finishedAt, fetchErr := lastFetch.Get()
+	if summary == nil {
+		return nil
+	}
+
+	var errorMessage interface{}
+	if fetchErr != nil {
+		errorMessage = fetchErr.Error()
+	}
+
+	return map[string]interface{}{
+		"summary":     summary,
+		"duration_ms": summary.Duration.Milliseconds(),
+		"finished_at": finishedAt.UTC().Format(time.RFC3339),
+		"error":       errorMessage,
+	}
+}
+
+// parsePrimeTime returns the prime time hours from ?start= and ?end=,
+// defaulting to PRIMETIME_START and PRIMETIME_END (or 20-23 when those are
+// invalid). Hours must be 0-23 and differ.
+func parsePrimeTime(c echo.Context) (int, int, error) {
+	startHour := envInt("PRIMETIME_START", DefaultPrimeTimeStart)
+	endHour := envInt("PRIMETIME_END", DefaultPrimeTimeEnd)
+	if !validHour(startHour) || !validHour(endHour) || startHour == endHour {
+		startHour, endHour = DefaultPrimeTimeStart, DefaultPrimeTimeEnd
+	}
+
+	for _, param := range []struct {
+		name string
+		hour *int
+	}{{"start", &startHour}, {"end", &endHour}} {
+		v := c.QueryParam(param.name)
+		if v == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(v)
+		if err != nil || !validHour(parsed) {
+			return 0, 0, fmt.Errorf("invalid %s %q, must be an hour 0-23", param.name, v)
+		}
+		*param.hour = parsed
+	}
+
+	if startHour == endHour {
+		return 0, 0, fmt.Errorf("start and end must differ, got %d for both", startHour)
+	}
+	return startHour, endHour, nil
+}
+
+func validHour(hour int) bool {
+	return hour >= 0 && hour <= 23
+}
+
+// primeTimeWindow returns tonight's window for the given hours. A window
+// crossing midnight ends the next day, and while it is still running after
+// midnight it is the one that started the previous evening.
+func primeTimeWindow(now time.Time, startHour, endHour int) (time.Time, time.Time) {
+	day := now
+	if endHour < startHour && now.Hour() < endHour {
+		day = now.AddDate(0, 0, -1)
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), startHour, 0, 0, 0, now.Location())
+	end := time.Date(day.Year(), day.Month(), day.Day(), endHour, 0, 0, 0, now.Location())
+	if endHour < startHour {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
+
+// seriesProgramCounts counts the stored programs of each given series
+func seriesProgramCounts(app *pocketbase.PocketBase, series []*models.Record) (map[string]int, error) {
+	counts := make(map[string]int, len(series))
+	if len(series) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]interface{}, len(series))
+	for i, record := range series {
+		ids[i] = record.Id
+	}
+
+	var rows []struct {
+		Series string `db:"series"`
+		Count  int    `db:"count"`
+	}
+	err := app.Dao().DB().Select("series", "count(*) AS count").
+		From("programs").
+		Where(dbx.In("series", ids...)).
+		GroupBy("series").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.Series] = row.Count
+	}
+	return counts, nil
+}
+
+// newListResult builds a response in the same shape as PocketBase's own list API
+func newListResult(page, perPage, totalItems int, items []map[string]any) map[string]any {
+	totalPages := (totalItems + perPage - 1) / perPage
+
+	return map[string]any{
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": totalItems,
+		"totalPages": totalPages,
+		"items":      items,
+	}
+}
+
+// expandChannels exports records with their channel relation attached under
+// "expand", loading all referenced channels in a single query
+func expandChannels(app *pocketbase.PocketBase, records []*models.Record) ([]map[string]any, error) {
+	channelIDs := make([]string, 0, len(records))
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if channelID := record.GetString("channel"); channelID != "" && !seen[channelID] {
+			seen[channelID] = true
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+
+	channels := make(map[string]*models.Record, len(channelIDs))
+	if len(channelIDs) > 0 {
+		channelRecords, err := app.Dao().FindRecordsByIds("channels", channelIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range channelRecords {
+			channels[channel.Id] = channel
+		}
+	}
+
+	expandedRecords := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		data := record.PublicExport()
+
+		if channel, ok := channels[record.GetString("channel")]; ok {
+			data["expand"] = map[string]any{
+				"channel": channel.PublicExport(),
+			}
+		}
+
+		expandedRecords = append(expandedRecords, data)
+	}
+
+	return expandedRecords, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tokens"
+)
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	app := newTestApp(t)
+	router := newTestRouter(t, app)
+
+	// A user token is not an admin token
+	users, err := app.Dao().FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.NewRecord(users)
+	user.SetUsername("viewer")
+	user.SetEmail("viewer@example.com")
+	user.SetPassword("password123")
+	if err := app.Dao().SaveRecord(user); err != nil {
+		t.Fatal(err)
+	}
+	userToken, err := tokens.NewRecordAuthToken(app, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every route in the group, so one added later can't skip the check
+	adminRoutes := 0
+	for _, route := range router.Router().Routes() {
+		if !strings.HasPrefix(route.Path(), "/api/admin/") {
+			continue
+		}
+		adminRoutes++
+
+		for name, token := range map[string]string{"anonymous": "", "user": userToken} {
+			req := httptest.NewRequest(route.Method(), route.Path(), nil)
+			if token != "" {
+				req.Header.Set("Authorization", token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s %s as %s: status = %d, want 403", route.Method(), route.Path(), name, rec.Code)
+			}
+		}
+	}
+	if adminRoutes < 8 {
+		t.Errorf("found %d /api/admin routes, want at least 8", adminRoutes)
+	}
+}
+
+func TestAdminRoutesAllowAdmin(t *testing.T) {
+	app := newTestApp(t)
+	router := newTestRouter(t, app)
+
+	admin := &models.Admin{Email: "admin@example.com"}
+	admin.SetPassword("password123")
+	if err := app.Dao().SaveAdmin(admin); err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.NewAdminAuthToken(app, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/api/admin/fetch/summary", "/api/admin/trigger/status"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s as admin: status = %d, want 200: %s", path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestPrimeTimeWindow(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.March, day, hour, minute, 0, 0, helsinki)
+	}
+
+	cases := []struct {
+		name       string
+		now        time.Time
+		start, end int
+		wantStart  time.Time
+		wantEnd    time.Time
+	}{
+		{"afternoon", at(10, 15, 0), 20, 23, at(10, 20, 0), at(10, 23, 0)},
+		{"during", at(10, 21, 30), 20, 23, at(10, 20, 0), at(10, 23, 0)},
+		{"after the end", at(10, 23, 30), 20, 23, at(10, 20, 0), at(10, 23, 0)},
+		{"past midnight, before midnight", at(10, 22, 0), 21, 1, at(10, 21, 0), at(11, 1, 0)},
+		{"past midnight, still running", at(11, 0, 30), 21, 1, at(10, 21, 0), at(11, 1, 0)},
+		{"past midnight, next day", at(11, 9, 0), 21, 1, at(11, 21, 0), at(12, 1, 0)},
+		{"month boundary", at(1, 0, 15), 22, 2, time.Date(2026, time.February, 28, 22, 0, 0, 0, helsinki), at(1, 2, 0)},
+		// Clocks go forward at 03:00 on 29 March
+		{"over the DST change", at(28, 20, 0), 22, 4, at(28, 22, 0), at(29, 4, 0)},
+	}
+
+	for _, tc := range cases {
+		start, end := primeTimeWindow(tc.now, tc.start, tc.end)
+		if !start.Equal(tc.wantStart) || !end.Equal(tc.wantEnd) {
+			t.Errorf("%s: window = %v - %v, want %v - %v", tc.name, start, end, tc.wantStart, tc.wantEnd)
+		}
+	}
+}
+
+func TestParsePrimeTime(t *testing.T) {
+	t.Setenv("PRIMETIME_START", "19")
+	t.Setenv("PRIMETIME_END", "22")
+
+	cases := []struct {
+		query      string
+		start, end int
+		wantErr    bool
+	}{
+		{"", 19, 22, false},
+		{"?start=21", 21, 22, false},
+		{"?start=22&end=2", 22, 2, false},
+		{"?end=0", 19, 0, false},
+		{"?start=24", 0, 0, true},
+		{"?end=-1", 0, 0, true},
+		{"?start=evening", 0, 0, true},
+		{"?start=22", 0, 0, true},
+	}
+
+	e := echo.New()
+	for _, tc := range cases {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/tv/tonight"+tc.query, nil), httptest.NewRecorder())
+		start, end, err := parsePrimeTime(c)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %d-%d, want an error", tc.query, start, end)
+			}
+			continue
+		}
+		if err != nil || start != tc.start || end != tc.end {
+			t.Errorf("%q: got %d-%d, %v, want %d-%d", tc.query, start, end, err, tc.start, tc.end)
+		}
+	}
+}
+
+func TestParsePrimeTimeInvalidEnv(t *testing.T) {
+	e := echo.New()
+	for _, env := range [][2]string{{"25", "23"}, {"21", "21"}, {"x", "23"}} {
+		t.Setenv("PRIMETIME_START", env[0])
+		t.Setenv("PRIMETIME_END", env[1])
+
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/tv/tonight", nil), httptest.NewRecorder())
+		start, end, err := parsePrimeTime(c)
+		if err != nil || start != DefaultPrimeTimeStart || end != DefaultPrimeTimeEnd {
+			t.Errorf("PRIMETIME_START=%s PRIMETIME_END=%s: got %d-%d, %v, want the defaults", env[0], env[1], start, end, err)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	cases := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"", 10, false},
+		{"?limit=5", 5, false},
+		{"?limit=500", 50, false},
+		{"?limit=0", 0, true},
+		{"?limit=-3", 0, true},
+		{"?limit=many", 0, true},
+	}
+
+	e := echo.New()
+	for _, tc := range cases {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/tv/upnext"+tc.query, nil), httptest.NewRecorder())
+		got, err := parseLimit(c, 10, 50)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %d, want an error", tc.query, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%q: got %d, %v, want %d", tc.query, got, err, tc.want)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 	"github.com/pocketbase/pocketbase/forms"
 	"github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/models/schema"
@@ -14,8 +18,8 @@ func ensureCollections(app *pocketbase.PocketBase) error {
 	collections, err := app.Dao().FindCollectionsByNames("channels", "series", "programs", "fetch_logs")
 
 	if len(collections) == 4 {
-		// All collections exist
-		return nil
+		// All collections exist, bring older deployments up to date
+		return migrateCollections(app)
 	}
 
 	// Create collections
@@ -35,7 +39,15 @@ func ensureCollections(app *pocketbase.PocketBase) error {
 		return err
 	}
 
-	return nil
+	if err := createFavoritesCollection(app); err != nil {
+		return err
+	}
+
+	if err := createRemindersCollection(app); err != nil {
+		return err
+	}
+
+	return ensureField(app, "users", userReminderLeadField())
 }
 
 func createChannelsCollection(app *pocketbase.PocketBase) error {
@@ -68,10 +80,7 @@ func createChannelsCollection(app *pocketbase.PocketBase) error {
 			Required: false,
 			Options: &schema.SelectOptions{
 				MaxSelect: 1,
-				Values: []string{
-					"public", "commercial", "sports", "movies",
-					"kids", "music", "international", "documentary", "other",
-				},
+				Values:    ChannelCategories,
 			},
 		},
 		&schema.SchemaField{
@@ -84,6 +93,7 @@ func createChannelsCollection(app *pocketbase.PocketBase) error {
 			Type:     schema.FieldTypeBool,
 			Required: true,
 		},
+		channelManualOverrideField(),
 	)
 
 	// API rules - public read access
@@ -250,6 +260,7 @@ func createProgramsCollection(app *pocketbase.PocketBase) error {
 			Type:     schema.FieldTypeBool,
 			Required: true,
 		},
+		programCategoryField(),
 	)
 
 	// Create indexes for performance
@@ -259,6 +270,7 @@ func createProgramsCollection(app *pocketbase.PocketBase) error {
 		"CREATE INDEX idx_programs_end_time ON programs (end_time)",
 		"CREATE INDEX idx_programs_name ON programs (name)",
 		"CREATE INDEX idx_programs_series ON programs (series)",
+		programCategoryIndex,
 	}
 
 	form.ListRule = types.Pointer("")
@@ -312,6 +324,9 @@ func createFetchLogsCollection(app *pocketbase.PocketBase) error {
 				Min: types.Pointer(0.0),
 			},
 		},
+		fetchLogCountField("programs_created"),
+		fetchLogCountField("programs_updated"),
+		fetchLogCountField("programs_unchanged"),
 		&schema.SchemaField{
 			Name:     "error_message",
 			Type:     schema.FieldTypeText,
@@ -338,26 +353,354 @@ func createFetchLogsCollection(app *pocketbase.PocketBase) error {
 	return form.Submit()
 }
 
-func cleanupOldData(app *pocketbase.PocketBase, daysOld int) error {
+// favoritesOwnerRule limits favorites and reminders to the authenticated
+// user's own
+const favoritesOwnerRule = "@request.auth.id != '' && user = @request.auth.id"
+
+// createFavoritesCollection creates the users' favorited series, one record
+// per user and series. Records are only visible to and deletable by their
+// owner; updates are admin-only, a favorite is removed and re-added instead.
+func createFavoritesCollection(app *pocketbase.PocketBase) error {
+	usersCollection, err := app.Dao().FindCollectionByNameOrId("users")
+	if err != nil {
+		return err
+	}
+
+	seriesCollection, err := app.Dao().FindCollectionByNameOrId("series")
+	if err != nil {
+		return err
+	}
+
+	collection := &models.Collection{}
+	form := forms.NewCollectionUpsert(app, collection)
+
+	form.Name = "favorites"
+	form.Type = models.CollectionTypeBase
+	form.Schema = schema.NewSchema(
+		&schema.SchemaField{
+			Name:     "user",
+			Type:     schema.FieldTypeRelation,
+			Required: true,
+			Options: &schema.RelationOptions{
+				CollectionId:  usersCollection.Id,
+				CascadeDelete: true,
+				MaxSelect:     types.Pointer(1),
+			},
+		},
+		&schema.SchemaField{
+			Name:     "series",
+			Type:     schema.FieldTypeRelation,
+			Required: true,
+			Options: &schema.RelationOptions{
+				CollectionId:  seriesCollection.Id,
+				CascadeDelete: true,
+				MaxSelect:     types.Pointer(1),
+			},
+		},
+	)
+
+	form.Indexes = types.JsonArray[string]{
+		"CREATE UNIQUE INDEX idx_favorites_user_series ON favorites (user, series)",
+		"CREATE INDEX idx_favorites_series ON favorites (series)",
+	}
+
+	form.ListRule = types.Pointer(favoritesOwnerRule)
+	form.ViewRule = types.Pointer(favoritesOwnerRule)
+	form.CreateRule = types.Pointer("@request.auth.id != '' && @request.data.user = @request.auth.id")
+	form.DeleteRule = types.Pointer(favoritesOwnerRule)
+
+	return form.Submit()
+}
+
+// createRemindersCollection creates the reminders of upcoming favorite
+// programs, one per user and program, written by the reminders job. Users
+// can see their own and dismiss them through /api/tv/reminders/:id; the
+// record API can't delete them, else the job would record them again.
+func createRemindersCollection(app *pocketbase.PocketBase) error {
+	usersCollection, err := app.Dao().FindCollectionByNameOrId("users")
+	if err != nil {
+		return err
+	}
+
+	programsCollection, err := app.Dao().FindCollectionByNameOrId("programs")
+	if err != nil {
+		return err
+	}
+
+	collection := &models.Collection{}
+	form := forms.NewCollectionUpsert(app, collection)
+
+	form.Name = "reminders"
+	form.Type = models.CollectionTypeBase
+	form.Schema = schema.NewSchema(
+		&schema.SchemaField{
+			Name:     "user",
+			Type:     schema.FieldTypeRelation,
+			Required: true,
+			Options: &schema.RelationOptions{
+				CollectionId:  usersCollection.Id,
+				CascadeDelete: true,
+				MaxSelect:     types.Pointer(1),
+			},
+		},
+		&schema.SchemaField{
+			Name:     "program",
+			Type:     schema.FieldTypeRelation,
+			Required: true,
+			Options: &schema.RelationOptions{
+				CollectionId:  programsCollection.Id,
+				CascadeDelete: true,
+				MaxSelect:     types.Pointer(1),
+			},
+		},
+		&schema.SchemaField{
+			Name:     "remind_at",
+			Type:     schema.FieldTypeDate,
+			Required: true,
+		},
+		&schema.SchemaField{
+			Name:     "start_time",
+			Type:     schema.FieldTypeDate,
+			Required: true,
+		},
+		reminderDismissedField(),
+	)
+
+	form.Indexes = types.JsonArray[string]{
+		"CREATE UNIQUE INDEX idx_reminders_user_program ON reminders (user, program)",
+		"CREATE INDEX idx_reminders_remind_at ON reminders (remind_at)",
+	}
+
+	form.ListRule = types.Pointer(favoritesOwnerRule)
+	form.ViewRule = types.Pointer(favoritesOwnerRule)
+
+	return form.Submit()
+}
+
+// reminderDismissedField marks a reminder the user dismissed. Dismissed
+// reminders are kept, until their program is removed, so the job doesn't
+// record them again.
+func reminderDismissedField() *schema.SchemaField {
+	return &schema.SchemaField{
+		Name:     "dismissed",
+		Type:     schema.FieldTypeBool,
+		Required: false,
+	}
+}
+
+// userReminderLeadField lets a user override REMINDER_LEAD_MINUTES, 0 or
+// empty uses the default
+func userReminderLeadField() *schema.SchemaField {
+	return &schema.SchemaField{
+		Name:     "reminder_lead_minutes",
+		Type:     schema.FieldTypeNumber,
+		Required: false,
+		Options: &schema.NumberOptions{
+			Min:       types.Pointer(0.0),
+			Max:       types.Pointer(float64(MaxReminderLead / time.Minute)),
+			NoDecimal: true,
+		},
+	}
+}
+
+// ChannelCategories are the allowed values of channels.category
+var ChannelCategories = []string{
+	"public", "commercial", "sports", "movies",
+	"kids", "music", "international", "documentary", "other",
+}
+
+// isChannelCategory reports whether category is one of ChannelCategories
+func isChannelCategory(category string) bool {
+	for _, c := range ChannelCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+const programCategoryIndex = "CREATE INDEX idx_programs_category ON programs (category)"
+
+// programCategoryField is the free-text genre/category captured from the API
+func programCategoryField() *schema.SchemaField {
+	return &schema.SchemaField{
+		Name:     "category",
+		Type:     schema.FieldTypeText,
+		Required: false,
+		Options: &schema.TextOptions{
+			Max: types.Pointer(100),
+		},
+	}
+}
+
+// channelManualOverrideField marks channels curated by an admin. The weekly
+// update leaves their show_order, active and category alone.
+func channelManualOverrideField() *schema.SchemaField {
+	return &schema.SchemaField{
+		Name:     "manual_override",
+		Type:     schema.FieldTypeBool,
+		Required: false,
+	}
+}
+
+// fetchLogCountField is a non-negative per-fetch program counter
+func fetchLogCountField(name string) *schema.SchemaField {
+	return &schema.SchemaField{
+		Name:     name,
+		Type:     schema.FieldTypeNumber,
+		Required: false,
+		Options: &schema.NumberOptions{
+			Min: types.Pointer(0.0),
+		},
+	}
+}
+
+// migrateCollections adds fields and collections introduced after the
+// initial schema to databases created by older versions
+func migrateCollections(app *pocketbase.PocketBase) error {
+	if err := ensureField(app, "programs", programCategoryField(), programCategoryIndex); err != nil {
+		return err
+	}
+	if err := ensureField(app, "channels", channelManualOverrideField()); err != nil {
+		return err
+	}
+	for _, name := range []string{"programs_created", "programs_updated", "programs_unchanged"} {
+		if err := ensureField(app, "fetch_logs", fetchLogCountField(name)); err != nil {
+			return err
+		}
+	}
+	if err := ensureCollection(app, "favorites", createFavoritesCollection); err != nil {
+		return err
+	}
+	if err := ensureCollection(app, "reminders", createRemindersCollection); err != nil {
+		return err
+	}
+	if err := ensureField(app, "reminders", reminderDismissedField()); err != nil {
+		return err
+	}
+	return ensureField(app, "users", userReminderLeadField())
+}
+
+// ensureCollection creates a collection with create if it doesn't exist yet
+func ensureCollection(app *pocketbase.PocketBase, name string, create func(*pocketbase.PocketBase) error) error {
+	if _, err := app.Dao().FindCollectionByNameOrId(name); err == nil {
+		return nil
+	}
+	return create(app)
+}
+
+// ensureField adds a field (and its indexes) to an existing collection if it is missing
+func ensureField(app *pocketbase.PocketBase, collectionName string, field *schema.SchemaField, indexes ...string) error {
+	collection, err := app.Dao().FindCollectionByNameOrId(collectionName)
+	if err != nil {
+		return err
+	}
+
+	if collection.Schema.GetFieldByName(field.Name) != nil {
+		return nil
+	}
+
+	form := forms.NewCollectionUpsert(app, collection)
+	form.Schema.AddField(field)
+	form.Indexes = append(form.Indexes, indexes...)
+
+	return form.Submit()
+}
+
+// cleanupOldData deletes programs, fetch logs and archived responses older
+// than policy allows. Program age limits depend on the channel category.
+func cleanupOldData(app *pocketbase.PocketBase, policy RetentionPolicy) error {
 	// Delete old programs
-	_, err := app.Dao().DB().NewQuery(`
-		DELETE FROM programs
-		WHERE datetime(start_time) < datetime('now', '-' || {:days} || ' days')
-	`).Bind(dbx.Params{
-		"days": daysOld,
-	}).Execute()
+	_, err := app.Dao().DB().Delete("programs", policy.programRetentionExp()).Execute()
 
 	if err != nil {
 		return err
 	}
 
+	if err := deleteOrphanReminders(app.Dao().DB()); err != nil {
+		return err
+	}
+
 	// Delete old fetch logs
 	_, err = app.Dao().DB().NewQuery(`
 		DELETE FROM fetch_logs
 		WHERE datetime(created) < datetime('now', '-' || {:days} || ' days')
 	`).Bind(dbx.Params{
-		"days": daysOld,
+		"days": policy.FetchLogDays,
 	}).Execute()
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Delete old raw API responses
+	return cleanupArchive(policy.ProgramDays)
+}
+
+// PurgeLogMarker prefixes the error_message of fetch_logs entries written by
+// purgePrograms, so they can be told apart from real fetches
+const PurgeLogMarker = "purge:"
+
+// notPurgeLog excludes purge audit entries from fetch_logs queries
+var notPurgeLog = dbx.NewExp("error_message NOT LIKE {:purgeMarker}", dbx.Params{"purgeMarker": PurgeLogMarker + "%"})
+
+// purgePrograms deletes programs starting in [from, to) in one transaction
+// and records the purge in fetch_logs, returning how many were removed. The
+// successful fetch logs of the purged days go too, so the next fetch
+// collects those days again instead of skipping them as recently fetched.
+func purgePrograms(app *pocketbase.PocketBase, from, to time.Time) (int64, error) {
+	fromDT, err := types.ParseDateTime(from)
+	if err != nil {
+		return 0, err
+	}
+	toDT, err := types.ParseDateTime(to)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		result, err := txDao.DB().NewQuery(`
+			DELETE FROM programs
+			WHERE start_time >= {:from} AND start_time < {:to}
+		`).Bind(dbx.Params{
+			"from": fromDT.String(),
+			"to":   toDT.String(),
+		}).Execute()
+		if err != nil {
+			return err
+		}
+		if removed, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		if err := deleteOrphanReminders(txDao.DB()); err != nil {
+			return err
+		}
+
+		_, err = txDao.DB().Delete("fetch_logs", dbx.And(
+			dbx.HashExp{"success": true},
+			dbx.Between("target_date", from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")),
+			notPurgeLog,
+		)).Execute()
+		if err != nil {
+			return err
+		}
+
+		collection, err := txDao.FindCollectionByNameOrId("fetch_logs")
+		if err != nil {
+			return err
+		}
+		record := models.NewRecord(collection)
+		record.Set("target_date", from.Format("20060102"))
+		record.Set("success", true)
+		record.Set("programs_count", removed)
+		record.Set("error_message", fmt.Sprintf("%s removed programs %s to %s",
+			PurgeLogMarker, from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02")))
+		return txDao.SaveRecord(record)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Search result grouping, chosen with ?groupBy=
+const (
+	SearchGroupNone   = ""
+	SearchGroupSeries = "series"
+)
+
+// searchCondition matches programs on active channels whose name contains
+// q and that haven't ended yet, optionally of one series and of one
+// program category
+func searchCondition(q, seriesID, category string) dbx.Expression {
+	conditions := []dbx.Expression{
+		// dbx.Like escapes % and _ in q
+		dbx.Like("p.name", q),
+		dbx.NewExp("p.end_time > {:now}", dbx.Params{"now": types.NowDateTime().String()}),
+		dbx.HashExp{"c.active": true},
+	}
+	if seriesID != "" {
+		conditions = append(conditions, dbx.HashExp{"p.series": seriesID})
+	}
+	if category != "" {
+		conditions = append(conditions, dbx.HashExp{"p.category": category})
+	}
+	return dbx.And(conditions...)
+}
+
+// searchPrograms returns one page of programs matching q, soonest first,
+// with their channels expanded, and the total number of matches
+func searchPrograms(app *pocketbase.PocketBase, q, seriesID, category string, page, perPage int) ([]map[string]any, int, error) {
+	where := searchCondition(q, seriesID, category)
+
+	var totalItems int
+	err := app.Dao().DB().Select("count(*)").
+		From("programs p").
+		InnerJoin("channels c", dbx.NewExp("c.id = p.channel")).
+		Where(where).
+		Row(&totalItems)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := []*models.Record{}
+	err = app.Dao().RecordQuery("programs").
+		Select("p.*").
+		From("programs p").
+		InnerJoin("channels c", dbx.NewExp("c.id = p.channel")).
+		Where(where).
+		OrderBy("p.start_time ASC", "p.id ASC").
+		Limit(int64(perPage)).
+		Offset(int64((page - 1) * perPage)).
+		All(&records)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, err := expandChannels(app, records)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, totalItems, nil
+}
+
+// searchProgramsBySeries is searchPrograms with the matches of a series
+// collapsed into one entry holding the match count and its next airing.
+// Programs without a series are entries of their own. Entries are ordered
+// by their next airing; ?series= on the search returns a series' programs.
+func searchProgramsBySeries(app *pocketbase.PocketBase, q, category string, page, perPage int) ([]map[string]any, int, error) {
+	where := searchCondition(q, "", category)
+
+	// Programs without a series group on their own id
+	group := "COALESCE(NULLIF(p.series, ''), p.id)"
+
+	var totalItems int
+	err := app.Dao().DB().Select("count(DISTINCT "+group+")").
+		From("programs p").
+		InnerJoin("channels c", dbx.NewExp("c.id = p.channel")).
+		Where(where).
+		Row(&totalItems)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// With MIN(), SQLite takes the bare p.id from the row holding the minimum,
+	// which is the group's next airing
+	var rows []struct {
+		Series string `db:"series"`
+		NextID string `db:"next_id"`
+		Count  int    `db:"count"`
+	}
+	err = app.Dao().DB().Select(group+" AS grp", "p.series AS series", "p.id AS next_id", "count(*) AS count", "MIN(p.start_time) AS next_start").
+		From("programs p").
+		InnerJoin("channels c", dbx.NewExp("c.id = p.channel")).
+		Where(where).
+		GroupBy("grp").
+		OrderBy("next_start ASC", "next_id ASC").
+		Limit(int64(perPage)).
+		Offset(int64((page - 1) * perPage)).
+		All(&rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return []map[string]any{}, totalItems, nil
+	}
+
+	programIDs := make([]string, 0, len(rows))
+	seriesIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		programIDs = append(programIDs, row.NextID)
+		if row.Series != "" {
+			seriesIDs = append(seriesIDs, row.Series)
+		}
+	}
+
+	records, err := app.Dao().FindRecordsByIds("programs", programIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	expanded, err := expandChannels(app, records)
+	if err != nil {
+		return nil, 0, err
+	}
+	programs := make(map[string]map[string]any, len(records))
+	for i, record := range records {
+		programs[record.Id] = expanded[i]
+	}
+
+	series := make(map[string]*models.Record, len(seriesIDs))
+	if len(seriesIDs) > 0 {
+		seriesRecords, err := app.Dao().FindRecordsByIds("series", seriesIDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, record := range seriesRecords {
+			series[record.Id] = record
+		}
+	}
+
+	items := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		next, ok := programs[row.NextID]
+		if !ok {
+			continue
+		}
+		if row.Series == "" {
+			items = append(items, map[string]any{
+				"type":    "program",
+				"program": next,
+			})
+			continue
+		}
+
+		item := map[string]any{
+			"type":        "series",
+			"series_id":   row.Series,
+			"name":        next["name"],
+			"count":       row.Count,
+			"next_airing": next,
+		}
+		if record, ok := series[row.Series]; ok {
+			item["name"] = record.GetString("name")
+		}
+		items = append(items, item)
+	}
+	return items, totalItems, nil
+}
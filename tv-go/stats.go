@@ -0,0 +1,161 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const DefaultStatsCacheTTL = 60 * time.Second
+
+// UpcomingCountsTTL is how long per-channel upcoming program counts are cached
+const UpcomingCountsTTL = 5 * time.Minute
+
+// statsCache is shared by the stats route and the collector, which
+// invalidates it after a successful fetch run
+var statsCache = NewStatsCache(time.Duration(envInt("STATS_CACHE_TTL", int(DefaultStatsCacheTTL.Seconds()))) * time.Second)
+
+// upcomingCounts caches, per channel, how many programs start in the next
+// 24 hours for /api/tv/channels
+var upcomingCounts = NewUpcomingCountsCache(UpcomingCountsTTL)
+
+// StatsCache keeps the computed /api/tv/stats response in memory for a short TTL
+type StatsCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	stats    map[string]interface{}
+	computed time.Time
+}
+
+func NewStatsCache(ttl time.Duration) *StatsCache {
+	return &StatsCache{ttl: ttl}
+}
+
+// Get returns the cached stats, recomputing them if the cache is empty or expired
+func (s *StatsCache) Get(app *pocketbase.PocketBase) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stats == nil || time.Since(s.computed) > s.ttl {
+		s.stats = computeStats(app)
+		s.computed = time.Now()
+	}
+
+	return s.stats
+}
+
+// Invalidate drops the cached stats so the next request recomputes them
+func (s *StatsCache) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats = nil
+}
+
+func computeStats(app *pocketbase.PocketBase) map[string]interface{} {
+	stats := make(map[string]interface{})
+
+	// Count programs
+	var programCount int
+	err := app.Dao().DB().Select("count(*)").
+		From("programs").
+		Row(&programCount)
+	if err == nil {
+		stats["total_programs"] = programCount
+	}
+
+	// Count channels
+	var channelCount int
+	err = app.Dao().DB().Select("count(*)").
+		From("channels").
+		Where(dbx.HashExp{"active": true}).
+		Row(&channelCount)
+	if err == nil {
+		stats["total_channels"] = channelCount
+	}
+
+	// Count series
+	var seriesCount int
+	err = app.Dao().DB().Select("count(*)").
+		From("series").
+		Row(&seriesCount)
+	if err == nil {
+		stats["total_series"] = seriesCount
+	}
+
+	return stats
+}
+
+// UpcomingCountsCache keeps per-channel counts of programs in the next 24h
+type UpcomingCountsCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	counts   map[string]int
+	computed time.Time
+}
+
+func NewUpcomingCountsCache(ttl time.Duration) *UpcomingCountsCache {
+	return &UpcomingCountsCache{ttl: ttl}
+}
+
+// Get returns the cached counts, recomputing them if empty or expired
+func (u *UpcomingCountsCache) Get(app *pocketbase.PocketBase) (map[string]int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.counts == nil || time.Since(u.computed) > u.ttl {
+		counts, err := computeUpcomingCounts(app)
+		if err != nil {
+			return nil, err
+		}
+		u.counts = counts
+		u.computed = time.Now()
+	}
+
+	return u.counts, nil
+}
+
+// Invalidate drops the cached counts so the next request recomputes them
+func (u *UpcomingCountsCache) Invalidate() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.counts = nil
+}
+
+func computeUpcomingCounts(app *pocketbase.PocketBase) (map[string]int, error) {
+	now := time.Now()
+	from, err := types.ParseDateTime(now)
+	if err != nil {
+		return nil, err
+	}
+	to, err := types.ParseDateTime(now.Add(24 * time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Channel string `db:"channel"`
+		Count   int    `db:"count"`
+	}
+	err = app.Dao().DB().Select("channel", "count(*) AS count").
+		From("programs").
+		Where(dbx.NewExp("start_time >= {:from} AND start_time < {:to}", dbx.Params{
+			"from": from.String(),
+			"to":   to.String(),
+		})).
+		GroupBy("channel").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Channel] = row.Count
+	}
+	return counts, nil
+}
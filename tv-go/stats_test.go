@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/migrate"
+)
+
+// newTestApp bootstraps a PocketBase app in a temporary data directory,
+// with the system migrations applied and the tv collections created
+func newTestApp(t testing.TB) *pocketbase.PocketBase {
+	t.Helper()
+	app := pocketbase.NewWithConfig(pocketbase.Config{
+		DefaultDataDir:  t.TempDir(),
+		HideStartBanner: true,
+	})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	runner, err := migrate.NewRunner(app.DB(), migrations.AppMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureCollections(app); err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// newTestRouter serves the custom routes of app
+func newTestRouter(t testing.TB, app *pocketbase.PocketBase) *echo.Echo {
+	t.Helper()
+	router, err := apis.InitApi(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := NewJobTracker()
+	t.Cleanup(func() { jobs.Shutdown(5 * time.Second) })
+	if err := setupCustomRoutes(app, &core.ServeEvent{App: app, Router: router}, jobs); err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+// insertRows adds rows to a table directly, bypassing record validation
+func insertRows(t testing.TB, app *pocketbase.PocketBase, table string, rows ...dbx.Params) {
+	t.Helper()
+	for _, row := range rows {
+		if _, err := app.Dao().DB().Insert(table, row).Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStatsRouteReturnsCounts(t *testing.T) {
+	app := newTestApp(t)
+	insertRows(t, app, "channels",
+		dbx.Params{"id": "1", "name": "Yle TV1", "active": true},
+		dbx.Params{"id": "2", "name": "MTV3", "active": true},
+		dbx.Params{"id": "3", "name": "Old channel", "active": false},
+	)
+	insertRows(t, app, "series",
+		dbx.Params{"id": "s1", "name": "Uutiset"},
+		dbx.Params{"id": "s2", "name": "Salatut elämät"},
+	)
+	for _, id := range []string{"p1", "p2", "p3", "p4", "p5"} {
+		insertRows(t, app, "programs", dbx.Params{"id": id, "channel": "1", "name": "Program " + id})
+	}
+
+	statsCache.Invalidate()
+	t.Cleanup(statsCache.Invalidate)
+
+	rec := httptest.NewRecorder()
+	newTestRouter(t, app).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tv/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var stats map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]float64{"total_programs": 5, "total_channels": 2, "total_series": 2} {
+		got, ok := stats[key].(float64)
+		if !ok {
+			t.Errorf("%s = %#v, want a number", key, stats[key])
+		} else if got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// XMLTVTimeout bounds downloading an XMLTV_SOURCE URL, guides can be large
+const XMLTVTimeout = 2 * time.Minute
+
+// xmltvTimeLayouts are the XMLTV date formats, with and without an offset
+var xmltvTimeLayouts = []string{"20060102150405 -0700", "20060102150405"}
+
+var ageLimitPattern = regexp.MustCompile(`\d+`)
+
+type xmltvChannel struct {
+	ID           string   `xml:"id,attr"`
+	DisplayNames []string `xml:"display-name"`
+	Icons        []struct {
+		Src string `xml:"src,attr"`
+	} `xml:"icon"`
+}
+
+type xmltvProgramme struct {
+	Start       string   `xml:"start,attr"`
+	Stop        string   `xml:"stop,attr"`
+	Channel     string   `xml:"channel,attr"`
+	Titles      []string `xml:"title"`
+	SubTitles   []string `xml:"sub-title"`
+	Descs       []string `xml:"desc"`
+	Categories  []string `xml:"category"`
+	EpisodeNums []struct {
+		System string `xml:"system,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"episode-num"`
+	Ratings []struct {
+		Value string `xml:"value"`
+	} `xml:"rating"`
+	StarRatings []struct {
+		Value string `xml:"value"`
+	} `xml:"star-rating"`
+}
+
+// xmltvSource reads a standard XMLTV guide from a local file or URL,
+// optionally gzipped. The guide is loaded once per collector and mapped
+// onto our IDs: a channel whose display name matches an existing channel
+// keeps that channel's ID, others get a stable ID hashed from the XMLTV
+// id, as do programs (channel and start) and series (title).
+type xmltvSource struct {
+	c        *TVCollector
+	location string
+
+	loaded   bool
+	loadErr  error
+	channels []APIChannel
+	programs map[string][]TVProgram // by "<channel id>/<YYYYMMDD>"
+}
+
+func newXMLTVSource(c *TVCollector, location string) *xmltvSource {
+	return &xmltvSource{c: c, location: location}
+}
+
+func (s *xmltvSource) Name() string { return SourceXMLTV }
+
+func (s *xmltvSource) Delay() time.Duration { return 0 }
+
+func (s *xmltvSource) Channels(ctx context.Context) ([]APIChannel, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	return s.channels, nil
+}
+
+func (s *xmltvSource) Programs(ctx context.Context, channelID, date string) ([]TVProgram, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	return s.programs[channelID+"/"+date], nil
+}
+
+// load reads and maps the guide on first use, remembering a failure so a
+// fetch run doesn't retry it for every channel/day
+func (s *xmltvSource) load(ctx context.Context) error {
+	if s.loaded {
+		return s.loadErr
+	}
+	s.loadErr = s.read(ctx)
+	s.loaded = ctx.Err() == nil
+	return s.loadErr
+}
+
+func (s *xmltvSource) read(ctx context.Context) error {
+	if s.location == "" {
+		return fmt.Errorf("XMLTV_SOURCE is not set")
+	}
+
+	body, err := s.open(ctx)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", s.location, err)
+	}
+	defer body.Close()
+
+	channels, programmes, err := parseXMLTV(body)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", s.location, err)
+	}
+
+	existing, err := s.existingChannelIDs()
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]string, len(channels))
+	s.channels = make([]APIChannel, 0, len(channels))
+	for i, ch := range channels {
+		id := ""
+		for _, name := range ch.DisplayNames {
+			if id = existing[normalizeChannelName(name)]; id != "" {
+				break
+			}
+		}
+		if id == "" {
+			id = strconv.Itoa(stableID(ch.ID))
+		}
+		ids[ch.ID] = id
+
+		numericID, _ := strconv.Atoi(id)
+		channel := APIChannel{ID: numericID, Name: ch.ID, ShowOrder: i + 1}
+		if len(ch.DisplayNames) > 0 {
+			channel.Name = strings.TrimSpace(ch.DisplayNames[0])
+		}
+		if len(ch.Icons) > 0 {
+			channel.LogoURL = ch.Icons[0].Src
+		}
+		if numericID == 0 {
+			// Existing channels with non-numeric IDs can't be listed, but
+			// their programs are still collected
+			continue
+		}
+		s.channels = append(s.channels, channel)
+	}
+
+	s.programs = mapProgrammes(programmes, ids)
+	return nil
+}
+
+// open returns the guide body, transparently gunzipping it
+func (s *xmltvSource) open(ctx context.Context) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://") {
+		ctx, cancel := context.WithTimeout(ctx, XMLTVTimeout)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.location, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		req.Header.Set("User-Agent", s.c.UserAgent)
+
+		// The collector client's timeout is meant for small API responses
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			cancel()
+			return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		body = readCloser{Reader: resp.Body, close: func() error { defer cancel(); return resp.Body.Close() }}
+	} else {
+		file, err := os.Open(s.location)
+		if err != nil {
+			return nil, err
+		}
+		body = file
+	}
+
+	buffered := bufio.NewReader(body)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		return readCloser{Reader: gz, close: body.Close}, nil
+	}
+	return readCloser{Reader: buffered, close: body.Close}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
+
+// existingChannelIDs maps normalized names to channel IDs, active channels
+// winning over retired ones
+func (s *xmltvSource) existingChannelIDs() (map[string]string, error) {
+	records := []*models.Record{}
+	err := s.c.app.Dao().RecordQuery("channels").OrderBy("active ASC").All(&records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channels: %w", err)
+	}
+
+	ids := make(map[string]string, len(records))
+	for _, record := range records {
+		ids[normalizeChannelName(record.GetString("name"))] = record.Id
+	}
+	return ids, nil
+}
+
+// parseXMLTV streams the <channel> and <programme> elements of a guide
+func parseXMLTV(r io.Reader) ([]xmltvChannel, []xmltvProgramme, error) {
+	var channels []xmltvChannel
+	var programmes []xmltvProgramme
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "channel":
+			var ch xmltvChannel
+			if err := decoder.DecodeElement(&ch, &start); err != nil {
+				return nil, nil, err
+			}
+			channels = append(channels, ch)
+		case "programme":
+			var p xmltvProgramme
+			if err := decoder.DecodeElement(&p, &start); err != nil {
+				return nil, nil, err
+			}
+			programmes = append(programmes, p)
+		}
+	}
+
+	if len(channels) == 0 {
+		return nil, nil, fmt.Errorf("no channels in guide")
+	}
+	return channels, programmes, nil
+}
+
+// mapProgrammes converts programmes of known channels to TVPrograms grouped
+// by channel and local start date. A missing stop time is taken from the
+// next programme on the channel; the last such one is dropped.
+func mapProgrammes(programmes []xmltvProgramme, channelIDs map[string]string) map[string][]TVProgram {
+	byChannel := make(map[string][]TVProgram)
+	for _, p := range programmes {
+		channelID, ok := channelIDs[p.Channel]
+		if !ok || len(p.Titles) == 0 {
+			continue
+		}
+		start, err := parseXMLTVTime(p.Start)
+		if err != nil {
+			continue
+		}
+		var stop int64
+		if t, err := parseXMLTVTime(p.Stop); err == nil {
+			stop = t.Unix()
+		}
+
+		prog := TVProgram{
+			ID:          stableID(p.Channel + "/" + p.Start),
+			Name:        strings.TrimSpace(p.Titles[0]),
+			Episode:     xmltvEpisode(p),
+			Description: firstOf(p.Descs),
+			Start:       start.Unix(),
+			Stop:        stop,
+			Category:    firstOf(p.Categories),
+		}
+		prog.Channel, _ = strconv.Atoi(channelID)
+		if prog.Episode != "" {
+			prog.SeriesID = stableID("series/" + strings.ToLower(prog.Name))
+		}
+		if len(p.Ratings) > 0 {
+			prog.AgeLimit, _ = strconv.Atoi(ageLimitPattern.FindString(p.Ratings[0].Value))
+		}
+		if len(p.StarRatings) > 0 {
+			prog.Rating = parseStarRating(p.StarRatings[0].Value)
+		}
+		byChannel[channelID] = append(byChannel[channelID], prog)
+	}
+
+	grouped := make(map[string][]TVProgram)
+	for channelID, programs := range byChannel {
+		sort.Slice(programs, func(i, j int) bool { return programs[i].Start < programs[j].Start })
+		for i, prog := range programs {
+			if prog.Stop == 0 {
+				if i+1 == len(programs) {
+					continue
+				}
+				prog.Stop = programs[i+1].Start
+			}
+			date := time.Unix(prog.Start, 0).Format("20060102")
+			grouped[channelID+"/"+date] = append(grouped[channelID+"/"+date], prog)
+		}
+	}
+	return grouped
+}
+
+func parseXMLTVTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range xmltvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid XMLTV time %q", value)
+}
+
+// xmltvEpisode is the sub-title, else the on-screen episode number, else
+// the xmltv_ns number ("0.4." is S1E5)
+func xmltvEpisode(p xmltvProgramme) string {
+	if subTitle := firstOf(p.SubTitles); subTitle != "" {
+		return subTitle
+	}
+	for _, num := range p.EpisodeNums {
+		if num.System == "onscreen" && strings.TrimSpace(num.Value) != "" {
+			return strings.TrimSpace(num.Value)
+		}
+	}
+	for _, num := range p.EpisodeNums {
+		if num.System != "xmltv_ns" {
+			continue
+		}
+		parts := strings.Split(strings.ReplaceAll(num.Value, " ", ""), ".")
+		if len(parts) < 2 {
+			continue
+		}
+		season, seasonErr := strconv.Atoi(strings.Split(parts[0], "/")[0])
+		episode, episodeErr := strconv.Atoi(strings.Split(parts[1], "/")[0])
+		switch {
+		case seasonErr == nil && episodeErr == nil:
+			return fmt.Sprintf("S%dE%d", season+1, episode+1)
+		case episodeErr == nil:
+			return fmt.Sprintf("E%d", episode+1)
+		}
+	}
+	return ""
+}
+
+// parseStarRating scales "n/m" to the 0-5 rating the API uses
+func parseStarRating(value string) int {
+	n, m, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return 0
+	}
+	num, err1 := strconv.ParseFloat(strings.TrimSpace(n), 64)
+	den, err2 := strconv.ParseFloat(strings.TrimSpace(m), 64)
+	if err1 != nil || err2 != nil || den <= 0 {
+		return 0
+	}
+	return int(math.Round(num / den * 5))
+}
+
+// stableID hashes key to a positive int that is the same on every run
+func stableID(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int(h.Sum64() >> 1)
+}
+
+func firstOf(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}